@@ -0,0 +1,69 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"fmt"
+)
+
+// Which diagnostics a group reports when several share a location.
+type Cascade int
+
+const (
+	// Report all diagnostics.
+	KeepAll Cascade = iota
+	// Report only the most severe diagnostic at each position.
+	OnePerPosition
+	// Report only the most severe diagnostic on each line.
+	OnePerLine
+)
+
+// Return the key identifying diagnostics that are duplicates of d.
+func (d Diag) key() string {
+	end := ""
+	if d.End != nil {
+		end = d.End.String()
+	}
+	return fmt.Sprintf("%s\x00%s\x00%d\x00%s", d.posString(), end, d.Kind, d.Message)
+}
+
+// Return the key identifying the location of d under cascade mode m, or the
+// empty string if d has no position.
+func (m Cascade) location(d Diag) string {
+	if d.Pos == nil {
+		return ""
+	}
+
+	raw := d.Raw()
+	if m == OnePerLine {
+		return fmt.Sprintf("%s:%d", raw.Filename(), raw.Line())
+	}
+	return fmt.Sprintf("%s:%d:%d:%d", raw.Filename(), raw.Line(), raw.Column(), raw.Offset())
+}
+
+// Return the sorted diagnostics ds with cascaded diagnostics removed. Among
+// diagnostics sharing a location, the first of the most severe is kept.
+// Diagnostics without positions are always kept.
+func (m Cascade) filter(ds []Diag) []Diag {
+	if m == KeepAll {
+		return ds
+	}
+
+	best := map[string]Diag{}
+	for _, d := range ds {
+		loc := m.location(d)
+		if have, ok := best[loc]; !ok || d.Kind.Rank() < have.Kind.Rank() {
+			best[loc] = d
+		}
+	}
+
+	kept := []Diag{}
+	for _, d := range ds {
+		if m.location(d) == "" || best[m.location(d)] == d {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
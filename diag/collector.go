@@ -0,0 +1,84 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"sort"
+	"sync"
+)
+
+// A Collector gathers diagnostics from concurrently executing passes.
+//
+// A diagnostic group is not safe for concurrent use, so each goroutine obtains
+// its own shard from the collector and adds diagnostics to that:
+//
+//	coll := diag.NewCollector(diags)
+//	for _, fn := range functions {
+//		go func(fn *Function) {
+//			typeCheck(fn, coll.Shard(fn.Name))
+//		}(fn)
+//	}
+//	...
+//	diags = coll.Diags()
+//
+// Shards share the sorting criteria, policies, and suppressions of the base
+// group. Because shards are merged in key order, the merged group does not
+// depend on the order in which goroutines were scheduled.
+//
+// Each shard enforces the base group's MaxErrors separately, and the limit is
+// enforced again when the shards are merged, keeping the errors of shards
+// with lower keys. The base group's
+// Policy should not be modified while shards are in use.
+type Collector struct {
+	mu     sync.Mutex
+	base   Diags
+	shards map[string]Diags
+}
+
+// Return a fresh collector whose shards are derived from base.
+func NewCollector(base Diags) *Collector {
+	return &Collector{
+		base:   base,
+		shards: map[string]Diags{},
+	}
+}
+
+// Return the shard having the given key, creating it if necessary.
+//
+// Shards are not safe for concurrent use. Goroutines that share a key must
+// synchronize their use of the shard themselves.
+func (cl *Collector) Shard(key string) Diags {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	sh, ok := cl.shards[key]
+	if !ok {
+		sh = cl.base.derive()
+		cl.shards[key] = sh
+	}
+	return sh
+}
+
+// Return a fresh diagnostic group combining the base group with all shards,
+// merged in key order.
+//
+// This should be called only once all goroutines adding to shards have
+// finished. The shards are flushed (see Sink) before they are merged.
+func (cl *Collector) Diags() Diags {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	keys := make([]string, 0, len(cl.shards))
+	for key := range cl.shards {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	merged := cl.base.derive().With(cl.base)
+	for _, key := range keys {
+		merged = merged.With(cl.shards[key].Flush())
+	}
+	return merged
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/bitc-lang/go-compileutil/position"
+)
+
+func TestCollector(t *testing.T) {
+	var expect string
+
+	for round := 0; round < 10; round++ {
+		base := New()
+		base.Dedup = false
+		base.AddInfo(position.Pos("x", 1, 1), "Starting")
+
+		coll := NewCollector(base)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				sh := coll.Shard(fmt.Sprintf("fn%d", i))
+				// Every shard reports at the same position, so the output
+				// order is decided by the merge.
+				sh.AddError(position.Pos("x", 2, 1), "Does not compute!").
+					AddNote(nil, fmt.Sprintf("in fn%d", i))
+			}(i)
+		}
+		wg.Wait()
+
+		diags := coll.Diags()
+		if !diags.HasError || len(diags.diags) != 9 {
+			t.Fatalf("Collector did not merge all shards")
+		}
+
+		if round == 0 {
+			expect = diags.String()
+		} else if diags.String() != expect {
+			t.Fatalf("Merged output depends on scheduling:\n%s", diags)
+		}
+	}
+}
+
+func TestCollectorLimit(t *testing.T) {
+	base := New()
+	base.MaxErrors = 3
+	coll := NewCollector(base)
+
+	for i := 0; i < 4; i++ {
+		sh := coll.Shard(fmt.Sprintf("fn%d", i))
+		for j := 0; j < 3; j++ {
+			sh.AddError(position.Pos("x", 10*i+j+1, 1), "Does not compute!")
+		}
+		sh.AddError(position.Pos("x", 10*i+9, 1), "Over the shard limit")
+	}
+
+	diags := coll.Diags()
+	if diags.Count(Error) != 3 || !diags.LimitReached {
+		t.Fatalf("Merged group holds %d errors, expected 3", diags.Count(Error))
+	}
+
+	limits := diags.Filter(func(d Diag) bool { return d.limit })
+	if len(limits) != 1 || diags.Len() != 4 {
+		t.Fatalf("Merged group did not report the error limit once:\n%s", diags)
+	}
+	if diags.All()[2].Line() != 3 {
+		t.Fatalf("Merged group did not keep the errors of the first shard:\n%s", diags)
+	}
+}
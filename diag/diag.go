@@ -5,11 +5,12 @@
 // Package diag implements a traditional multi-level diagnostic system
 // compatible with the Go error interface.
 //
-// The diag package implements four levels of message;
+// The diag package implements the following levels of message, in order of
+// decreasing severity:
 //
-//   - Fatal: diagnostics that immediately terminate the program, for use
-//     when a program cannot make progress at all or a problem is so severe
-//     that no output should be produced.
+//   - Fatal: diagnostics that immediately terminate the current computation,
+//     for use when a program cannot make progress at all or a problem is so
+//     severe that no output should be produced (see OnFatal and CatchFatal).
 //   - Errors: diagnostics that lead to program exit without output, but are
 //     sufficiently recoverable that further useful diagnostic output remains
 //     possible before exiting.
@@ -17,16 +18,28 @@
 //     not an error.
 //   - Info: diagnostics that provide informational messages to the user, such
 //     as copyright notices, versions information, and the like.
+//   - Note, Help, and Hint: stand-alone remarks, advice, and suggestions that
+//     editors and other tools may present less prominently.
 //
-// When printed, or returned as an error value, diagnostics are organized in
-// sorted by input position. If a sorting function is not explicitly provided,
-// position strings are assumed to take the form
-//
-//	filename:line:column
+// Applications can register further kinds (see RegisterKind).
 //
 // Diagnostic messages (type Diag) are organized into groups (type Diags).
 // Groups can be merged using the Diags.With() message. This is useful primarily
-// in compiler-like applications that implement rollback with roll-forward.
+// in compiler-like applications that implement rollback with roll-forward, and
+// groups can also be marked and rolled back directly (see Diags.Mark). A
+// group decides which diagnostics it records through its Policy, suppressions,
+// error limit, and deduplication settings. When printed, or returned as an
+// error value, the diagnostics of a group are sorted by input position (see
+// SortByPosition).
+//
+// A diagnostic may carry notes (type Note), suggested edits (type Suggestion),
+// a code declared in a Registry, and an underlying cause. Groups can be
+// rendered with source excerpts (type Renderer), translated (type Catalog),
+// streamed to a Sink as they are added, or exported as JSON, SARIF, or
+// Language Server Protocol payloads.
+//
+// Diagnostic groups are not safe for concurrent use. Passes that run in
+// multiple goroutines should obtain a shard per goroutine from a Collector.
 package diag
 
 import (
@@ -39,7 +52,10 @@ import (
 )
 
 type Position position.Position
+type Span position.Span
 
+// The kind, or severity, of a diagnostic. Applications may register further
+// kinds using RegisterKind.
 type DiagKind int
 
 const (
@@ -53,41 +69,166 @@ const (
 
 	// Informational diagnostics that do not terminate execution.
 	Info
+	// Stand-alone remarks, such as "inlining foo into bar". Named NoteKind
+	// because Note is the type of notes attached to other diagnostics.
+	NoteKind
+	// Stand-alone advice on how to improve the input.
+	Help
+	// Unobtrusive suggestions, such as those an editor shows as hints.
+	Hint
 )
 
-//go:generate stringer -type=DiagKind
-
 type diag struct {
-	Pos     string
-	Kind    DiagKind
-	Message string
+	Pos      Position // Position of the diagnostic, or nil if there is none.
+	End      Position // End of the diagnosed span, or nil for a single position.
+	Kind     DiagKind
+	Code     string // Diagnostic code (see Registry), or the empty string.
+	Category string // Category of the code (see Descriptor), or the empty string.
+	Message  string // Message text, in English.
+	Notes    []Note // Notes and help entries attached to this diagnostic.
+
+	MessageID string // Identifier of a translatable message (see AddMessage).
+	Args      []any  // Arguments of the translatable message.
+
+	Suggestions []Suggestion // Suggested source changes.
+	Cause       error        // Underlying error, or nil (see Unwrap).
+
+	limit     bool // The "too many errors" diagnostic, which is not counted.
+	forwarded bool // Sent to sinks ahead of its turn (see emitFatal).
 }
 
 type diags struct {
 	// Function to be used when sorting positions.
 	//
-	// Different applications form position strings in different ways, so the
+	// Different applications order their inputs in different ways, so the
 	// sorting algorithm is app specific (and sometimes library specific).
 	// The defaul algorithm is worth a try before customizing.
-	Sort     func([]Diag) []Diag
-	HasError bool
-	diags    []Diag
+	Sort func([]Diag) []Diag
+	// Function called after a fatal diagnostic has been recorded.
+	//
+	// The default policy is PanicOnFatal. If the function returns, Add returns
+	// normally and processing continues.
+	OnFatal func(Diags, Diag)
+	// Policy adjusting the severity of added diagnostics, or nil.
+	Policy *Policy
+	// Maximum number of errors to record, or zero for no limit.
+	//
+	// Once the limit is reached, further errors are dropped, a single "too
+	// many errors" diagnostic is recorded, and LimitReached is set.
+	MaxErrors    int
+	LimitReached bool
+	// Drop diagnostics identical in position, severity, and message to one
+	// already recorded. Enabled by default.
+	Dedup bool
+	// Which diagnostics to report when several share a position or line.
+	Cascade Cascade
+	// Sinks to which diagnostics are forwarded as they are added (see Sink).
+	Sinks []Sink
+	// Catalog of translated messages and the locale in which diagnostics are
+	// printed (see Localize).
+	Catalog     Catalog
+	Locale      string
+	HasError    bool
+	HasWarnings bool
+	counts      map[DiagKind]int // Number of diagnostics recorded of each kind.
+	diags       []Diag
+	last        Diag // Most recently added diagnostic, for attaching notes.
+	suppressed  int  // Number of diagnostics discarded by policy.
+
+	suppressions []*suppression  // In-source suppression regions.
+	seen         map[string]bool // Keys of recorded diagnostics, for Dedup.
+	marks        int             // Number of unresolved marks.
+	unsent       int             // Index of the first diagnostic not yet sent to Sinks.
 }
 
 type Diag = *diag   // Export as a heap-allocated type
 type Diags = *diags // Export as a heap-allocated type
 
-// Return a string represnting a specific diagnostic message.
+// Return a string represnting a specific diagnostic message, followed by one
+// line for each of its notes.
 func (d Diag) String() string {
-	return fmt.Sprintf("%s: %s: %s", d.Pos, d.Kind, d.Message)
+	s := []string{d.headline(palette{})}
+	for _, n := range d.Notes {
+		s = append(s, n.String())
+	}
+	return strings.Join(s, "\n")
+}
+
+// Return the position string of the diagnostic, or the empty string if it has
+// no position.
+func (d Diag) posString() string {
+	if d.Pos == nil {
+		return ""
+	}
+	return d.Pos.String()
+}
+
+// Return the position at which this diagnostic was reported, or nil if it has
+// no position.
+func (d Diag) Position() Position {
+	return d.Pos
+}
+
+// Return the span of input covered by this diagnostic, or nil if it was
+// reported at a single position.
+func (d Diag) Span() Span {
+	if d.Pos == nil || d.End == nil {
+		return nil
+	}
+	return position.SpanOf(d.Pos, d.End)
+}
+
+// Return the file name associated with this diagnostic, or the empty string if
+// it has no position.
+func (d Diag) Filename() string {
+	if d.Pos == nil {
+		return ""
+	}
+	return d.Pos.Filename()
+}
+
+// Return the line number (starting at 1) of this diagnostic, or 0 if it has no
+// position.
+func (d Diag) Line() int {
+	if d.Pos == nil {
+		return 0
+	}
+	return d.Pos.Line()
+}
+
+// Return the column number (starting at 1) of this diagnostic, or 0 if it has
+// no position.
+func (d Diag) Column() int {
+	if d.Pos == nil {
+		return 0
+	}
+	return d.Pos.Column()
+}
+
+// Return the byte offset (starting at 0) of this diagnostic, or -1 if the
+// offset is not known.
+func (d Diag) Offset() int {
+	if d.Pos == nil {
+		return -1
+	}
+	return d.Pos.Offset()
+}
+
+// Return the position of this diagnostic without adjustment for line number
+// directives, or nil if it has no position.
+func (d Diag) Raw() Position {
+	if d.Pos == nil {
+		return nil
+	}
+	return d.Pos.Raw()
 }
 
 // Return a string containing all diagnostics in the diagnostic group, sorted
 // by the active sorting algorithm.
 func (d Diags) String() string {
 	s := []string{}
-	for _, d := range d.Sort(d.diags) {
-		s = append(s, d.String())
+	for _, dg := range d.sorted() {
+		s = append(s, Localize(dg, d.Catalog, d.Locale).String())
 	}
 
 	s = append(s, "") // Ensures trailing newline
@@ -95,6 +236,14 @@ func (d Diags) String() string {
 	return strings.Join(s, "\n")
 }
 
+// Return the diagnostics of the group, sorted by the active sorting algorithm.
+//
+// The group's own diagnostics remain in the order in which they were added.
+func (c Diags) sorted() []Diag {
+	ds := append([]Diag{}, c.diags...)
+	return c.Cascade.filter(c.Sort(ds))
+}
+
 func (d Diags) Empty() bool {
 	return len(d.diags) == 0
 }
@@ -117,23 +266,41 @@ func (d Diags) AsError() error {
 	return nil
 }
 
-func defaultSort(d []Diag) []Diag {
-	sort.Slice(d, func(i1, i2 int) bool {
+// The default sorting function.
+var defaultSort = SortByPosition()
+
+// Sort diagnostics lexically by position string, and then by severity and
+// message.
+//
+// This was the default sorting function prior to diagnostics retaining their
+// positions. It remains useful for applications whose position strings take
+// the form
+//
+//	filename:line:column
+//
+// with zero-padded line and column numbers.
+func SortByPositionString(d []Diag) []Diag {
+	sort.SliceStable(d, func(i1, i2 int) bool {
 		d1 := d[i1]
 		d2 := d[i2]
 
-		if d1.Pos > d2.Pos {
+		// Diagnostics without positions sort last
+		if (d1.Pos == nil) != (d2.Pos == nil) {
+			return d2.Pos == nil
+		}
+
+		p1 := d1.posString()
+		p2 := d2.posString()
+
+		if p1 > p2 {
 			return false
 		}
-		if d1.Pos < d2.Pos {
+		if p1 < p2 {
 			return true
 		}
 		// Positions are equal = sort further by severity
-		if d1.Kind > d2.Kind {
-			return false
-		}
-		if d1.Kind < d2.Kind {
-			return true
+		if d1.Kind != d2.Kind {
+			return d1.Kind.before(d2.Kind)
 		}
 		// Severities are equal
 		return d1.Message < d2.Message
@@ -142,31 +309,187 @@ func defaultSort(d []Diag) []Diag {
 	return d
 }
 
+// Return a sorting function that orders diagnostics by file, then numerically
+// by line, column, and offset, and then by severity, code, and message.
+//
+// Files named in files sort first, in the order given. Other files follow in
+// lexical order. Diagnostics without positions sort last.
+func SortByPosition(files ...string) func([]Diag) []Diag {
+	fileOrder := map[string]int{}
+	for ndx, f := range files {
+		if _, ok := fileOrder[f]; !ok {
+			fileOrder[f] = ndx
+		}
+	}
+
+	compareFiles := func(f1, f2 string) int {
+		o1, ok1 := fileOrder[f1]
+		o2, ok2 := fileOrder[f2]
+		switch {
+		case ok1 && ok2:
+			return o1 - o2
+		case ok1:
+			return -1
+		case ok2:
+			return 1
+		default:
+			return strings.Compare(f1, f2)
+		}
+	}
+
+	less := func(d1, d2 Diag) bool {
+		// Diagnostics without positions sort last
+		if (d1.Pos == nil) != (d2.Pos == nil) {
+			return d2.Pos == nil
+		}
+
+		if d1.Pos != nil {
+			if c := compareFiles(d1.Filename(), d2.Filename()); c != 0 {
+				return c < 0
+			}
+			if d1.Line() != d2.Line() {
+				return d1.Line() < d2.Line()
+			}
+			if d1.Column() != d2.Column() {
+				return d1.Column() < d2.Column()
+			}
+			if d1.Offset() != d2.Offset() {
+				return d1.Offset() < d2.Offset()
+			}
+		}
+
+		// Positions are equal = sort further by severity
+		if d1.Kind != d2.Kind {
+			return d1.Kind.before(d2.Kind)
+		}
+		if d1.Code != d2.Code {
+			return d1.Code < d2.Code
+		}
+		return d1.Message < d2.Message
+	}
+
+	return func(d []Diag) []Diag {
+		sort.SliceStable(d, func(i1, i2 int) bool {
+			return less(d[i1], d[i2])
+		})
+		return d
+	}
+}
+
 // Return a new diagnostic group.
 func New() Diags {
 	c := &diags{
 		HasError: false,
 		Sort:     defaultSort,
+		OnFatal:  PanicOnFatal,
+		Dedup:    true,
+		Cascade:  KeepAll,
+		counts:   map[DiagKind]int{},
 		diags:    []Diag{},
+		seen:     map[string]bool{},
 	}
 	return c
 }
 
 // Add a diagnostic with the specified location, severity, and message payload
 func (c Diags) Add(where Position, kind DiagKind, msg string) Diags {
-	diag := &diag{Pos: where.String(), Kind: kind, Message: msg}
-	c.diags = append(c.diags, diag)
-	switch kind {
-	case Error:
-		c.HasError = true
-	case Fatal:
-		fmt.Fprintln(os.Stderr, diag.String())
-		os.Exit(-1)
+	return c.add(&diag{Pos: where, Kind: kind, Message: msg})
+}
+
+// Add a diagnostic covering the specified span, with the given severity and
+// message payload.
+func (c Diags) AddSpan(where Span, kind DiagKind, msg string) Diags {
+	return c.add(&diag{Pos: where.Start(), End: where.End(), Kind: kind, Message: msg})
+}
+
+// Record diagnostic d in the group, applying in-source suppressions, the
+// severity policy, deduplication, the error limit, and the fatal diagnostic
+// policy, and forward the previously recorded diagnostic to the group's sinks.
+func (c Diags) add(d Diag) Diags {
+	if c.isSuppressed(d) {
+		c.suppressed++
+		c.last = nil
+		return c
+	}
+
+	if c.Policy != nil {
+		kind, keep := c.Policy.apply(d)
+		if !keep {
+			c.suppressed++
+			c.last = nil
+			return c
+		}
+		d.Kind = kind
+	}
+
+	if c.Dedup && d.Kind != Fatal && c.seen[d.key()] {
+		c.last = nil
+		return c
+	}
+
+	if c.overLimit(d) {
+		if !c.LimitReached {
+			c.LimitReached = true
+			c.record(c.limitDiag())
+			c.emit(false)
+		}
+		c.last = nil
+		return c
+	}
+
+	c.record(d)
+	c.emit(false)
+	if d.Kind == Fatal {
+		c.emitFatal(d)
+		c.OnFatal(c, d)
 	}
 
 	return c
 }
 
+// Return true iff d is a non-fatal error that would exceed the group's error
+// limit.
+func (c Diags) overLimit(d Diag) bool {
+	return d.Kind != Fatal && d.Kind.IsError() && c.MaxErrors > 0 && c.errorCount() >= c.MaxErrors
+}
+
+// Return the diagnostic reporting that the group's error limit was reached.
+func (c Diags) limitDiag() Diag {
+	return &diag{Kind: Error, limit: true, Message: fmt.Sprintf(
+		"too many errors (limit %d); further errors are not reported", c.MaxErrors)}
+}
+
+// Append diagnostic d to the group and update the group's summary state. The
+// "too many errors" diagnostic is not counted.
+func (c Diags) record(d Diag) {
+	c.diags = append(c.diags, d)
+	c.seen[d.key()] = true
+	c.last = d
+	if d.limit {
+		return
+	}
+	c.counts[d.Kind]++
+	if d.Kind.IsError() {
+		c.HasError = true
+	} else if d.Kind == Warning {
+		c.HasWarnings = true
+	}
+}
+
+// Add a diagnostic at the specified location using the code and default
+// severity of desc.
+func (c Diags) AddCoded(where Position, desc *Descriptor, msg string) Diags {
+	return c.add(&diag{Pos: where, Kind: desc.kind(),
+		Code: desc.Code, Category: desc.Category, Message: msg})
+}
+
+// Add a diagnostic covering the specified span using the code and default
+// severity of desc.
+func (c Diags) AddSpanCoded(where Span, desc *Descriptor, msg string) Diags {
+	return c.add(&diag{Pos: where.Start(), End: where.End(),
+		Kind: desc.kind(), Code: desc.Code, Category: desc.Category, Message: msg})
+}
+
 // Issue a fatal diagnostic giving the specified location and message.
 func (c Diags) AddFatal(where Position, msg string) Diags {
 	return c.Add(where, Fatal, msg)
@@ -193,15 +516,136 @@ func (c Diags) AddInfo(where Position, msg string) Diags {
 // Return a fresh diagnostic group combining the diagnostics of two existing
 // groups.
 //
-// The sorting criteria of the receiver is used by the fresh diagnostic group.
+// The sorting criteria and policies of the receiver are used by the fresh
+// diagnostic group. If the receiver deduplicates, diagnostics of d that are
+// identical to diagnostics of the receiver are dropped. If the receiver limits
+// errors, errors beyond the limit are dropped, those of the receiver first.
+// The fresh group reports reaching an error limit at most once.
+//
+// Diagnostics that the two groups have not yet forwarded to their sinks (see
+// Sink) are forwarded to the sinks of the fresh group instead, and will not be
+// forwarded by the two groups.
 func (c Diags) With(d Diags) Diags {
-	fresh := []Diag{}
-	fresh = append(fresh, c.diags...)
-	fresh = append(fresh, d.diags...)
+	fresh := c.derive()
+	var pending []Diag // Diagnostics not yet forwarded by c or d.
+	var limit Diag     // The first "too many errors" diagnostic of c or d.
+	limitSent := false // Whether c or d forwarded such a diagnostic.
+	for _, g := range []Diags{c, d} {
+		for ndx, dg := range g.diags {
+			unsent := ndx >= g.unsent && !dg.forwarded
+			switch {
+			case dg.limit:
+				if limit == nil {
+					limit = dg
+				}
+				limitSent = limitSent || !unsent
+			case fresh.overLimit(dg):
+				fresh.LimitReached = true
+			case !fresh.Dedup || !fresh.seen[dg.key()]:
+				fresh.record(dg)
+				if unsent {
+					pending = append(pending, dg)
+				}
+			}
+		}
+		if g.marks == 0 {
+			g.unsent = len(g.diags)
+		}
+	}
+
+	if fresh.LimitReached {
+		limit = fresh.limitDiag()
+	}
+	if limit != nil {
+		fresh.record(limit)
+		if !limitSent {
+			pending = append(pending, limit)
+		}
+	}
+
+	fresh.last = nil
+	fresh.unsent = len(fresh.diags)
+	for _, dg := range pending {
+		for _, s := range fresh.Sinks {
+			s.Emit(dg)
+		}
+	}
+	fresh.HasError = fresh.HasError || c.HasError || d.HasError
+	fresh.HasWarnings = fresh.HasWarnings || c.HasWarnings || d.HasWarnings
+	fresh.LimitReached = fresh.LimitReached || c.LimitReached || d.LimitReached
+	fresh.suppressed = c.suppressed + d.suppressed
+
+	for _, s := range d.suppressions {
+		fresh.addSuppression(s)
+	}
+
+	return fresh
+}
 
+// Return a fresh, empty diagnostic group having the sorting criteria,
+// policies, sinks, locale, and suppressions of the receiver.
+func (c Diags) derive() Diags {
 	return &diags{
-		HasError: c.HasError || d.HasError,
-		Sort:     c.Sort,
-		diags:    fresh,
+		HasError:     false,
+		Sort:         c.Sort,
+		OnFatal:      c.OnFatal,
+		Policy:       c.Policy,
+		MaxErrors:    c.MaxErrors,
+		Dedup:        c.Dedup,
+		Cascade:      c.Cascade,
+		Sinks:        c.Sinks,
+		Catalog:      c.Catalog,
+		Locale:       c.Locale,
+		counts:       map[DiagKind]int{},
+		seen:         map[string]bool{},
+		diags:        []Diag{},
+		suppressions: append([]*suppression{}, c.suppressions...),
 	}
 }
+
+// Return the number of diagnostics that have been discarded by the group's
+// policy or by in-source suppressions.
+func (c Diags) Suppressed() int {
+	return c.suppressed
+}
+
+// The panic value raised by PanicOnFatal.
+type FatalError struct {
+	Diag  Diag  // The fatal diagnostic
+	Diags Diags // The diagnostic group to which it was added
+}
+
+func (e *FatalError) Error() string {
+	return e.Diag.String()
+}
+
+// Fatal diagnostic policy that panics with a *FatalError. This is the default
+// policy for new diagnostic groups.
+func PanicOnFatal(c Diags, d Diag) {
+	panic(&FatalError{Diag: d, Diags: c})
+}
+
+// Fatal diagnostic policy that prints the fatal diagnostic to os.Stderr and
+// exits the program. Intended for command line programs.
+func ExitOnFatal(c Diags, d Diag) {
+	fmt.Fprintln(os.Stderr, d.String())
+	os.Exit(-1)
+}
+
+// Call f, recovering from any panic raised by PanicOnFatal.
+//
+// Returns the *FatalError that terminated f, or nil if f returned normally.
+// Panics that were not raised by PanicOnFatal are propagated.
+func CatchFatal(f func()) (fe *FatalError) {
+	defer func() {
+		if r := recover(); r != nil {
+			var ok bool
+			if fe, ok = r.(*FatalError); !ok {
+				panic(r)
+			}
+		}
+	}()
+
+	f()
+	return nil
+}
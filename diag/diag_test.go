@@ -88,3 +88,232 @@ func TestDiagSort(t *testing.T) {
 		t.Fatalf("Expected basic output does not validate")
 	}
 }
+
+func TestDiagPosition(t *testing.T) {
+	diags := New()
+
+	diags.AddError(position.OffsetPos("x", 10, 3, 117), "Does not compute!")
+	d := diags.diags[0]
+
+	if d.Filename() != "x" || d.Line() != 10 || d.Column() != 3 || d.Offset() != 117 {
+		t.Fatalf("Diagnostic position not retained: %s", d.Position())
+	}
+
+	if d.Raw().String() != "x:10:3 (117)" {
+		t.Fatalf("Bad raw position for diagnostic: %s", d.Raw())
+	}
+}
+
+func TestDiagFatal(t *testing.T) {
+	diags := New()
+
+	fe := CatchFatal(func() {
+		diags.AddFatal(position.Pos("x", 3, 1), "Cannot continue!")
+		t.Fatalf("Fatal diagnostic did not panic")
+	})
+	if fe == nil || fe.Diags != diags || fe.Diag.Message != "Cannot continue!" {
+		t.Fatalf("Fatal panic not recovered")
+	}
+	if diags.AsError() == nil {
+		t.Fatalf("Diags with fatal errors should return non-nil error value")
+	}
+
+	called := false
+	diags.OnFatal = func(c Diags, d Diag) { called = true }
+	diags.AddFatal(position.Pos("x", 4, 1), "Cannot continue either!")
+	if !called {
+		t.Fatalf("Fatal callback was not invoked")
+	}
+}
+
+const noteString = `x:1:5: Warning: x is never used
+x:3:1: Error: redefinition of x
+x:1:5: note: previous definition was here
+help: rename one of the definitions
+x:4:2: Info: Just so you know
+`
+
+func TestDiagNotes(t *testing.T) {
+	diags := New()
+
+	diags.AddError(position.Pos("x", 3, 1), "redefinition of x").
+		AddNote(position.Pos("x", 1, 5), "previous definition was here").
+		AddHelp(nil, "rename one of the definitions")
+	diags.AddInfo(position.Pos("x", 4, 2), "Just so you know")
+	diags.AddWarn(position.Pos("x", 1, 5), "x is never used")
+
+	if diags.String() != noteString {
+		t.Fatalf("Notes not printed with their parent:\n%s", diags)
+	}
+}
+
+const limitString = `x:1:1: Error: Error 1
+x:2:1: Error: Error 2
+x:9:1: Warning: Warnings are not limited
+Error: too many errors (limit 2); further errors are not reported
+`
+
+func TestDiagLimit(t *testing.T) {
+	diags := New()
+	diags.MaxErrors = 2
+
+	diags.AddError(position.Pos("x", 1, 1), "Error 1")
+	diags.AddError(position.Pos("x", 2, 1), "Error 2")
+	if diags.LimitReached {
+		t.Fatalf("Error limit reached early")
+	}
+
+	diags.AddError(position.Pos("x", 3, 1), "Error 3").
+		AddNote(position.Pos("x", 1, 1), "Dropped with its error")
+	diags.AddError(position.Pos("x", 4, 1), "Error 4")
+	diags.AddWarn(position.Pos("x", 9, 1), "Warnings are not limited")
+	if !diags.LimitReached {
+		t.Fatalf("Error limit not reached")
+	}
+
+	if diags.Count(Error) != 2 || diags.Summary() != "2 errors, 1 warning generated" {
+		t.Fatalf("Error limit diagnostic was counted: %s", diags.Summary())
+	}
+
+	if diags.String() != limitString {
+		t.Fatalf("Unexpected diagnostics under error limit:\n%s", diags)
+	}
+}
+
+const cascadeString = `x:1:1: Error: Unexpected token
+x:1:1: Warning: Ignoring the rest of the line
+x:1:9: Error: Unterminated expression
+x:2:1: Warning: Deprecated syntax
+`
+
+const cascadePosString = `x:1:1: Error: Unexpected token
+x:1:9: Error: Unterminated expression
+x:2:1: Warning: Deprecated syntax
+`
+
+const cascadeLineString = `x:1:1: Error: Unexpected token
+x:2:1: Warning: Deprecated syntax
+`
+
+func TestDiagCascade(t *testing.T) {
+	diags1 := New()
+	diags2 := New()
+
+	// As produced by two attempts of a backtracking parser:
+	for _, d := range []Diags{diags1, diags2} {
+		d.AddError(position.Pos("x", 1, 1), "Unexpected token")
+		d.AddWarn(position.Pos("x", 1, 1), "Ignoring the rest of the line")
+		d.AddError(position.Pos("x", 1, 1), "Unexpected token")
+	}
+	diags2.AddError(position.Pos("x", 1, 9), "Unterminated expression")
+	diags2.AddWarn(position.Pos("x", 2, 1), "Deprecated syntax")
+
+	diags := diags1.With(diags2)
+	if diags.String() != cascadeString {
+		t.Fatalf("Unexpected deduplicated diagnostics:\n%s", diags)
+	}
+
+	diags.Cascade = OnePerPosition
+	if diags.String() != cascadePosString {
+		t.Fatalf("Unexpected diagnostics with one per position:\n%s", diags)
+	}
+
+	diags.Cascade = OnePerLine
+	if diags.String() != cascadeLineString {
+		t.Fatalf("Unexpected diagnostics with one per line:\n%s", diags)
+	}
+}
+
+func TestDiagRollback(t *testing.T) {
+	diags := New()
+	diags.AddWarn(position.Pos("x", 1, 2), "Danger, Will Robinson!")
+
+	outer := diags.Mark()
+	diags.AddError(position.Pos("x", 2, 27), "Does not compute!")
+
+	inner := diags.Mark()
+	diags.AddNote(position.Pos("x", 1, 1), "Discarded note")
+	diags.AddInfo(position.Pos("x", 1, 5), "That's great information!")
+	diags.Rollback(inner)
+
+	if diags.String() != basicString {
+		t.Fatalf("Rollback of inner mark did not restore diagnostics:\n%s", diags)
+	}
+
+	inner = diags.Mark()
+	diags.AddInfo(position.Pos("x", 1, 5), "That's great information!")
+	diags.Commit(inner)
+	if diags.String() != infoString {
+		t.Fatalf("Commit of inner mark did not keep diagnostics:\n%s", diags)
+	}
+
+	diags.Rollback(outer)
+	if diags.HasError || len(diags.diags) != 1 {
+		t.Fatalf("Rollback of outer mark did not restore diagnostics:\n%s", diags)
+	}
+
+	// Rolled back diagnostics are no longer duplicates
+	diags.AddError(position.Pos("x", 2, 27), "Does not compute!")
+	if diags.String() != basicString {
+		t.Fatalf("Rolled back diagnostic could not be re-added:\n%s", diags)
+	}
+}
+
+const numericSortString = `b.bit:2:1: Warning: In the first file
+a.bit:9:1: Error: Line nine
+a.bit:10:2: Error: Line ten
+a.bit:10:2 (99): Error: With an offset
+c.bit:1:1: Info: In an unlisted file
+`
+
+func TestDiagNumericSort(t *testing.T) {
+	diags := New()
+	diags.Sort = SortByPosition("b.bit", "a.bit")
+
+	diags.AddInfo(position.Pos("c.bit", 1, 1), "In an unlisted file")
+	diags.AddError(position.OffsetPos("a.bit", 10, 2, 99), "With an offset")
+	diags.AddError(position.Pos("a.bit", 10, 2), "Line ten")
+	diags.AddError(position.Pos("a.bit", 9, 1), "Line nine")
+	diags.AddWarn(position.Pos("b.bit", 2, 1), "In the first file")
+
+	if diags.String() != numericSortString {
+		t.Fatalf("Unexpected numeric sort order:\n%s", diags)
+	}
+
+	diags.Sort = SortByPositionString
+	if diags.diags[0].Filename() != "c.bit" || diags.sorted()[0].Filename() != "a.bit" {
+		t.Fatalf("Unexpected lexical sort order:\n%s", diags)
+	}
+}
+
+func TestDiagCounts(t *testing.T) {
+	diags := New()
+	if diags.Summary() != "no errors generated" {
+		t.Fatalf("Unexpected summary for empty diags: %s", diags.Summary())
+	}
+
+	diags.AddWarn(position.Pos("x", 1, 2), "Danger, Will Robinson!")
+	if !diags.HasWarnings || diags.HasError {
+		t.Fatalf("Warning not reflected in HasWarnings")
+	}
+
+	m := diags.Mark()
+	diags.AddError(position.Pos("x", 2, 27), "Does not compute!")
+	diags.Rollback(m)
+	if diags.Count(Error) != 0 {
+		t.Fatalf("Rollback did not restore error count")
+	}
+
+	diags2 := New()
+	diags2.AddError(position.Pos("x", 2, 27), "Does not compute!")
+	diags2.AddWarn(position.Pos("x", 3, 1), "Danger again!")
+	diags2.AddInfo(position.Pos("x", 4, 1), "Informative")
+
+	diags = diags.With(diags2)
+	if diags.Count(Error) != 1 || diags.Count(Warning) != 2 || diags.Count(Info) != 1 {
+		t.Fatalf("Merged counts are wrong")
+	}
+	if diags.Summary() != "1 error, 2 warnings generated" {
+		t.Fatalf("Unexpected summary: %s", diags.Summary())
+	}
+}
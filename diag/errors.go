@@ -0,0 +1,74 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"errors"
+)
+
+// Implement the error interface, so that a single diagnostic can be returned
+// as an error value.
+func (d Diag) Error() string {
+	return d.String()
+}
+
+// Return the underlying cause of the diagnostic, or nil if it has none.
+func (d Diag) Unwrap() error {
+	return d.Cause
+}
+
+// Return the reported diagnostics of the group as error values, in the order
+// returned by All. This allows errors.Is and errors.As to search the group,
+// and the causes of its diagnostics.
+func (c Diags) Unwrap() []error {
+	errs := []error{}
+	for _, d := range c.sorted() {
+		errs = append(errs, d)
+	}
+	return errs
+}
+
+// Return a diagnostic of the given kind at the specified location (which may
+// be nil) whose message is the text of err and whose cause is err. Returns nil
+// if err is nil.
+func FromError(where Position, kind DiagKind, err error) Diag {
+	if err == nil {
+		return nil
+	}
+	return &diag{Pos: where, Kind: kind, Message: err.Error(), Cause: err}
+}
+
+// Add a diagnostic converted from err by FromError.
+//
+// If err is itself a diagnostic group, its diagnostics are added individually
+// with their original positions and severities instead, and where and kind are
+// ignored. The added diagnostics are copies, so that the receiver's policy and
+// later notes do not alter the diagnostics of the other group. The other
+// group's "too many errors" diagnostic is not added; the receiver's own error
+// limit applies instead.
+//
+// Does nothing if err is nil.
+func (c Diags) AddCause(where Position, kind DiagKind, err error) Diags {
+	if err == nil {
+		return c
+	}
+
+	var group Diags
+	if errors.As(err, &group) {
+		for _, d := range group.diags {
+			if d.limit {
+				continue
+			}
+			cp := *d
+			cp.forwarded = false
+			cp.Notes = append([]Note(nil), d.Notes...)
+			cp.Suggestions = append([]Suggestion(nil), d.Suggestions...)
+			c.add(&cp)
+		}
+		return c
+	}
+
+	return c.add(FromError(where, kind, err))
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/bitc-lang/go-compileutil/position"
+)
+
+func TestErrorInterop(t *testing.T) {
+	diags := New()
+	diags.AddWarn(position.Pos("x", 1, 2), "Danger, Will Robinson!")
+	diags.AddCause(position.Pos("x", 2, 1), Error, fmt.Errorf("reading x: %w", io.ErrUnexpectedEOF))
+
+	err := diags.AsError()
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("errors.Is does not find the cause of a diagnostic")
+	}
+	if errors.Is(err, io.EOF) {
+		t.Fatalf("errors.Is finds an unrelated error")
+	}
+
+	var d Diag
+	if !errors.As(err, &d) || d.Kind != Warning {
+		t.Fatalf("errors.As does not find the diagnostics of a group")
+	}
+
+	other := New()
+	other.AddCause(nil, Info, err)
+	if other.Len() != 2 || !other.HasError {
+		t.Fatalf("AddCause did not merge a diagnostic group")
+	}
+	if other.diags[1].Message != "reading x: unexpected EOF" {
+		t.Fatalf("Unexpected message from converted error: %s", other.diags[1].Message)
+	}
+}
+
+func TestAddCauseCopies(t *testing.T) {
+	src := New()
+	src.AddWarn(position.Pos("x", 1, 1), "Unused variable")
+
+	dst := New()
+	dst.Policy = NewPolicy()
+	dst.Policy.WarningsAsErrors = true
+	dst.AddCause(nil, Info, src).AddNote(nil, "Added to the merged copy")
+
+	if dst.Count(Error) != 1 || !dst.HasError {
+		t.Fatalf("Policy was not applied to the added diagnostics")
+	}
+	if d := src.diags[0]; d.Kind != Warning || len(d.Notes) != 0 || src.HasError {
+		t.Fatalf("AddCause modified the diagnostics of the source group")
+	}
+
+	if dst.AddCause(nil, Error, nil).Len() != 1 || FromError(nil, Error, nil) != nil {
+		t.Fatalf("A nil error was converted to a diagnostic")
+	}
+}
+
+func TestAddCauseLimitAndFatal(t *testing.T) {
+	src := New()
+	src.OnFatal = func(Diags, Diag) {}
+	src.MaxErrors = 1
+	src.AddError(position.Pos("x", 1, 1), "First")
+	src.AddError(position.Pos("x", 2, 1), "Second")
+	m := src.Mark()
+	src.AddFatal(position.Pos("x", 3, 1), "Boom")
+	src.Commit(m)
+
+	rec := &Recorder{}
+	dst := New()
+	dst.OnFatal = func(Diags, Diag) {}
+	dst.MaxErrors = 1
+	dst.Sinks = []Sink{rec}
+	dst.AddCause(nil, Error, src).Flush()
+
+	if dst.Len() != 2 || dst.Count(Error) != 1 || dst.LimitReached {
+		t.Fatalf("Limit diagnostic of the source group was added:\n%s", dst)
+	}
+	if got := rec.Diags(); len(got) != 2 || got[1].Kind != Fatal {
+		t.Fatalf("Added fatal diagnostic was not sent: %v", got)
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/bitc-lang/go-compileutil/reader"
+)
+
+// How confident a suggestion is that it reflects what the user intended.
+//
+// Values are ordered from most to least confident.
+type Applicability int
+
+const (
+	// The suggestion is what the user intended, and can be applied
+	// mechanically.
+	MachineApplicable Applicability = iota
+	// The suggestion may not be what the user intended.
+	MaybeIncorrect
+	// The suggestion contains placeholders that the user must fill in.
+	HasPlaceholders
+	// The applicability of the suggestion is not known.
+	Unspecified
+)
+
+//go:generate stringer -type=Applicability
+
+// A replacement of the source text [Pos, End) by Text. An insertion has Pos
+// equal to End. A deletion has empty Text.
+type Edit struct {
+	Pos  Position
+	End  Position
+	Text string
+}
+
+// Return an edit replacing the text of span with text.
+func Replace(span Span, text string) Edit {
+	return Edit{Pos: span.Start(), End: span.End(), Text: text}
+}
+
+// Return an edit inserting text at where.
+func Insert(where Position, text string) Edit {
+	return Edit{Pos: where, End: where, Text: text}
+}
+
+// Return an edit deleting the text of span.
+func Delete(span Span) Edit {
+	return Edit{Pos: span.Start(), End: span.End(), Text: ""}
+}
+
+// A suggested change to the source, made up of non-overlapping edits.
+type Suggestion struct {
+	Message       string
+	Applicability Applicability
+	Edits         []Edit
+}
+
+// Attach a suggested change to the diagnostic.
+func (d Diag) AddSuggestion(msg string, app Applicability, edits ...Edit) Diag {
+	d.Suggestions = append(d.Suggestions,
+		Suggestion{Message: msg, Applicability: app, Edits: edits})
+	return d
+}
+
+// Attach a suggested change to the most recently added diagnostic in the
+// group.
+//
+// Does nothing if no diagnostic has been added to the group.
+func (c Diags) AddSuggestion(msg string, app Applicability, edits ...Edit) Diags {
+	if c.last != nil {
+		c.last.AddSuggestion(msg, app, edits...)
+	}
+	return c
+}
+
+// Return the edits for the named file from all suggestions in the group whose
+// applicability is at least as confident as app, in diagnostic order.
+//
+// Edits are selected by their raw (line directive unadjusted) file name, which
+// is the file in which ApplyEdits locates them. Edits without a position are
+// skipped.
+//
+// The edits of different suggestions may overlap, in which case ApplyEdits
+// will reject them.
+func (c Diags) Edits(filename string, app Applicability) []Edit {
+	edits := []Edit{}
+	for _, d := range c.sorted() {
+		for _, s := range d.Suggestions {
+			if s.Applicability > app {
+				continue
+			}
+			for _, e := range s.Edits {
+				if e.Pos != nil && e.Pos.Raw().Filename() == filename {
+					edits = append(edits, e)
+				}
+			}
+		}
+	}
+	return edits
+}
+
+// Return the full content of r.
+func readAll(r reader.Reader) ([]byte, error) {
+	end := reader.Offset(0)
+	for {
+		if _, err := r.ByteAt(end); err != nil {
+			break
+		}
+		end++
+	}
+	return r.Content(0, end)
+}
+
+// Return the content of r with the provided edits applied.
+//
+// Edit positions are located within r by offset or, if an edit position has no
+// offset, by line and column. It is an error for an edit to lack either
+// position. Edits must not overlap, though several insertions may be made at
+// the same position, in which case they are applied in the order given. An
+// insertion at the beginning of a replaced range is applied before the
+// replacement.
+func ApplyEdits(r reader.Reader, edits []Edit) ([]byte, error) {
+	content, err := readAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	type span struct {
+		begin, end reader.Offset
+		text       string
+		pos        Position
+	}
+
+	spans := []span{}
+	for _, e := range edits {
+		if e.Pos == nil || e.End == nil {
+			return nil, fmt.Errorf("edit has no position")
+		}
+		begin, ok1 := offsetOf(r, e.Pos.Raw())
+		end, ok2 := offsetOf(r, e.End.Raw())
+		if !ok1 || !ok2 || end < begin || int(end) > len(content) {
+			return nil, fmt.Errorf("edit %s: invalid range", e.Pos)
+		}
+		spans = append(spans, span{begin: begin, end: end, text: e.Text, pos: e.Pos})
+	}
+
+	sort.SliceStable(spans, func(i, j int) bool {
+		if spans[i].begin != spans[j].begin {
+			return spans[i].begin < spans[j].begin
+		}
+		return spans[i].end < spans[j].end
+	})
+
+	var out bytes.Buffer
+	at := reader.Offset(0)
+	for _, s := range spans {
+		if s.begin < at {
+			return nil, fmt.Errorf("edit %s overlaps a preceding edit", s.pos)
+		}
+		out.Write(content[at:s.begin])
+		out.WriteString(s.text)
+		at = s.end
+	}
+	out.Write(content[at:])
+
+	return out.Bytes(), nil
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bitc-lang/go-compileutil/position"
+	"github.com/bitc-lang/go-compileutil/reader"
+)
+
+func TestApplyEdits(t *testing.T) {
+	r, err := reader.OnString("let x = fo\nprint(x)")
+	if err != nil {
+		t.Fatalf("Error %v instantiating Reader on string", err)
+	}
+
+	diags := New()
+	diags.AddError(position.OffsetPos("<string>", 1, 9, 8), "undefined: fo").
+		AddSuggestion("did you mean foo?", MachineApplicable,
+			Replace(position.SpanOf(position.OffsetPos("<string>", 1, 9, 8),
+				position.OffsetPos("<string>", 1, 11, 10)), "foo"),
+			Insert(position.OffsetPos("<string>", 1, 11, 10), ";"))
+	diags.AddWarn(position.Pos("<string>", 2, 1), "print is deprecated").
+		AddSuggestion("remove the call", MaybeIncorrect,
+			Delete(position.SpanOf(position.Pos("<string>", 2, 1), position.Pos("<string>", 2, 9))))
+
+	out, err := ApplyEdits(r, diags.Edits("<string>", MachineApplicable))
+	if err != nil || string(out) != "let x = foo;\nprint(x)" {
+		t.Fatalf("Unexpected result applying edits (error %v): %q", err, out)
+	}
+
+	out, err = ApplyEdits(r, diags.Edits("<string>", Unspecified))
+	if err != nil || string(out) != "let x = foo;\n" {
+		t.Fatalf("Unexpected result applying edits (error %v): %q", err, out)
+	}
+
+	overlap := []Edit{
+		Delete(position.SpanOf(position.OffsetPos("<string>", 1, 1, 0), position.OffsetPos("<string>", 1, 6, 5))),
+		Delete(position.SpanOf(position.OffsetPos("<string>", 1, 5, 4), position.OffsetPos("<string>", 1, 8, 7))),
+	}
+	if _, err := ApplyEdits(r, overlap); err == nil {
+		t.Fatalf("Overlapping edits should be rejected")
+	}
+
+	// An insertion at the beginning of a replacement does not overlap it,
+	// whatever the order in which the two are given.
+	replace := Replace(position.SpanOf(position.OffsetPos("<string>", 1, 5, 4),
+		position.OffsetPos("<string>", 1, 6, 5)), "y")
+	insert := Insert(position.OffsetPos("<string>", 1, 5, 4), "!")
+	for _, edits := range [][]Edit{{replace, insert}, {insert, replace}} {
+		out, err := ApplyEdits(r, edits)
+		if err != nil || string(out) != "let !y = fo\nprint(x)" {
+			t.Fatalf("Unexpected result applying edits (error %v): %q", err, out)
+		}
+	}
+}
+
+// A position adjusted by a line directive to another file.
+type directivePos struct {
+	*position.BasicPos
+	file string
+}
+
+func (p directivePos) Filename() string       { return p.file }
+func (p directivePos) Raw() position.Position { return p.BasicPos }
+
+func TestEditsUnderLineDirective(t *testing.T) {
+	r, _ := reader.OnString("let x = fo\n")
+	at := directivePos{position.OffsetPos("<string>", 1, 9, 8), "x.bit"}
+	end := directivePos{position.OffsetPos("<string>", 1, 11, 10), "x.bit"}
+
+	diags := New()
+	diags.AddError(at, "undefined: fo").
+		AddSuggestion("did you mean foo?", MachineApplicable, Edit{Pos: at, End: end, Text: "foo"})
+
+	if len(diags.Edits("x.bit", MachineApplicable)) != 0 {
+		t.Fatalf("Edits were selected by adjusted file name")
+	}
+	out, err := ApplyEdits(r, diags.Edits("<string>", MachineApplicable))
+	if err != nil || string(out) != "let x = foo\n" {
+		t.Fatalf("Unexpected result applying edits (error %v): %q", err, out)
+	}
+
+	if _, err := ApplyEdits(r, []Edit{{Pos: at, Text: "oops"}}); err == nil {
+		t.Fatalf("Edit without an end position was accepted")
+	}
+
+	diags.AddSuggestion("broken", Unspecified, Edit{Text: "oops"})
+	if err := diags.WriteJSON(bytes.NewBuffer([]byte{})); err == nil {
+		t.Fatalf("Edit without a position was written as JSON")
+	}
+}
+
+func TestJSONSuggestions(t *testing.T) {
+	orig := New()
+	orig.AddError(position.OffsetPos("x", 1, 9, 8), "undefined: fo").
+		AddSuggestion("did you mean foo?", HasPlaceholders,
+			Insert(position.OffsetPos("x", 1, 11, 10), "o"))
+
+	buf := bytes.NewBuffer([]byte{})
+	if err := orig.WriteJSON(buf); err != nil {
+		t.Fatalf("Error %v writing JSON", err)
+	}
+
+	diags, err := ReadJSON(buf)
+	if err != nil {
+		t.Fatalf("Error %v reading JSON", err)
+	}
+
+	s := diags.diags[0].Suggestions
+	if len(s) != 1 || s[0].Applicability != HasPlaceholders ||
+		s[0].Edits[0].End.Offset() != 10 || s[0].Edits[0].Text != "o" {
+		t.Fatalf("JSON round trip does not preserve suggestions: %v", s)
+	}
+}
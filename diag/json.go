@@ -0,0 +1,280 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/bitc-lang/go-compileutil/position"
+)
+
+// The JSON form of a diagnostic:
+//
+//	{"file":"x.bit","line":2,"column":27,"offset":51,"kind":"Error","message":"Does not compute!"}
+//
+// Offsets are -1 when not known. The "code" and "category" fields are present
+// only for diagnostics that have them. Notes appear in a "notes" array, each having
+// the same position fields as the diagnostic together with a "label" and
+// "message". Suggested changes appear in a "suggestions" array, each having a
+// "message", an "applicability", and an array of "edits" giving the replaced
+// range and its replacement "text". Diagnostics that cover a span carry an
+// additional "end" object giving the line, column and offset of the end of the
+// span. Line and column fields are omitted when the diagnostic has no line
+// information. The diagnostic reporting that the group's error limit was
+// reached has a "limit" field of true.
+type jsonDiag struct {
+	File     string     `json:"file,omitempty"`
+	Line     int        `json:"line,omitempty"`
+	Column   int        `json:"column,omitempty"`
+	Offset   int        `json:"offset"`
+	End      *jsonEnd   `json:"end,omitempty"`
+	Kind     string     `json:"kind"`
+	Code     string     `json:"code,omitempty"`
+	Category string     `json:"category,omitempty"`
+	Message  string     `json:"message"`
+	Notes    []jsonNote `json:"notes,omitempty"`
+	Limit    bool       `json:"limit,omitempty"`
+
+	Suggestions []jsonSuggestion `json:"suggestions,omitempty"`
+}
+
+type jsonNote struct {
+	File    string   `json:"file,omitempty"`
+	Line    int      `json:"line,omitempty"`
+	Column  int      `json:"column,omitempty"`
+	Offset  int      `json:"offset"`
+	End     *jsonEnd `json:"end,omitempty"`
+	Label   string   `json:"label"`
+	Message string   `json:"message"`
+}
+
+type jsonEnd struct {
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+	Offset int `json:"offset"`
+}
+
+type jsonSuggestion struct {
+	Message       string     `json:"message"`
+	Applicability string     `json:"applicability"`
+	Edits         []jsonEdit `json:"edits"`
+}
+
+type jsonEdit struct {
+	File   string  `json:"file,omitempty"`
+	Line   int     `json:"line,omitempty"`
+	Column int     `json:"column,omitempty"`
+	Offset int     `json:"offset"`
+	End    jsonEnd `json:"end"`
+	Text   string  `json:"text"`
+}
+
+// Return the applicability having the given name.
+func applicabilityNamed(name string) (Applicability, error) {
+	for a := MachineApplicable; a <= Unspecified; a++ {
+		if a.String() == name {
+			return a, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown applicability %q", name)
+}
+
+// Return the diagnostic kind having the given name.
+func kindNamed(name string) (DiagKind, error) {
+	if k, ok := KindNamed(name); ok {
+		return k, nil
+	}
+	return 0, fmt.Errorf("unknown diagnostic kind %q", name)
+}
+
+func jsonEndOf(end Position) *jsonEnd {
+	return &jsonEnd{Line: end.Line(), Column: end.Column(), Offset: end.Offset()}
+}
+
+// Implement json.Marshaler.
+func (d Diag) MarshalJSON() ([]byte, error) {
+	jd := jsonDiag{
+		File:     d.Filename(),
+		Line:     d.Line(),
+		Column:   d.Column(),
+		Offset:   d.Offset(),
+		Kind:     d.Kind.String(),
+		Code:     d.Code,
+		Category: d.Category,
+		Message:  d.Message,
+		Limit:    d.limit,
+	}
+	if d.End != nil {
+		jd.End = jsonEndOf(d.End)
+	}
+
+	for _, n := range d.Notes {
+		jn := jsonNote{Offset: -1, Label: n.Label, Message: n.Message}
+		if n.Pos != nil {
+			jn.File = n.Pos.Filename()
+			jn.Line = n.Pos.Line()
+			jn.Column = n.Pos.Column()
+			jn.Offset = n.Pos.Offset()
+		}
+		if n.End != nil {
+			jn.End = jsonEndOf(n.End)
+		}
+		jd.Notes = append(jd.Notes, jn)
+	}
+
+	for _, s := range d.Suggestions {
+		js := jsonSuggestion{
+			Message:       s.Message,
+			Applicability: s.Applicability.String(),
+			Edits:         []jsonEdit{},
+		}
+		for _, e := range s.Edits {
+			if e.Pos == nil || e.End == nil {
+				return nil, fmt.Errorf("diagnostic %q: suggested edit has no position", d.Message)
+			}
+			js.Edits = append(js.Edits, jsonEdit{
+				File:   e.Pos.Filename(),
+				Line:   e.Pos.Line(),
+				Column: e.Pos.Column(),
+				Offset: e.Pos.Offset(),
+				End:    *jsonEndOf(e.End),
+				Text:   e.Text,
+			})
+		}
+		jd.Suggestions = append(jd.Suggestions, js)
+	}
+
+	return json.Marshal(&jd)
+}
+
+// Implement json.Unmarshaler.
+//
+// Positions of decoded diagnostics are position.BasicPos values.
+func (d Diag) UnmarshalJSON(b []byte) error {
+	jd := jsonDiag{Offset: -1}
+	if err := json.Unmarshal(b, &jd); err != nil {
+		return err
+	}
+
+	kind, err := kindNamed(jd.Kind)
+	if err != nil {
+		return err
+	}
+
+	*d = diag{Kind: kind, Code: jd.Code, Category: jd.Category, Message: jd.Message, limit: jd.Limit}
+	if jd.File != "" || jd.Line > 0 || jd.Offset >= 0 {
+		d.Pos = position.OffsetPos(jd.File, jd.Line, jd.Column, jd.Offset)
+		if jd.End != nil {
+			d.End = position.OffsetPos(jd.File, jd.End.Line, jd.End.Column, jd.End.Offset)
+		}
+	}
+
+	for _, jn := range jd.Notes {
+		n := Note{Label: jn.Label, Message: jn.Message}
+		if jn.File != "" || jn.Line > 0 || jn.Offset >= 0 {
+			n.Pos = position.OffsetPos(jn.File, jn.Line, jn.Column, jn.Offset)
+			if jn.End != nil {
+				n.End = position.OffsetPos(jn.File, jn.End.Line, jn.End.Column, jn.End.Offset)
+			}
+		}
+		d.Notes = append(d.Notes, n)
+	}
+
+	for _, js := range jd.Suggestions {
+		app, err := applicabilityNamed(js.Applicability)
+		if err != nil {
+			return err
+		}
+
+		s := Suggestion{Message: js.Message, Applicability: app}
+		for _, je := range js.Edits {
+			s.Edits = append(s.Edits, Edit{
+				Pos:  position.OffsetPos(je.File, je.Line, je.Column, je.Offset),
+				End:  position.OffsetPos(je.File, je.End.Line, je.End.Column, je.End.Offset),
+				Text: je.Text,
+			})
+		}
+		d.Suggestions = append(d.Suggestions, s)
+	}
+
+	return nil
+}
+
+// Write the diagnostics in the group to w as a single JSON array, sorted by
+// the active sorting algorithm.
+func (c Diags) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c.sorted())
+}
+
+// Write the diagnostics in the group to w as JSON lines (one JSON object per
+// line), sorted by the active sorting algorithm.
+func (c Diags) WriteJSONLines(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for _, d := range c.sorted() {
+		if err := enc.Encode(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Read a diagnostic group from a JSON array produced by WriteJSON.
+//
+// Fatal diagnostics are recorded without invoking the fatal diagnostic policy.
+func ReadJSON(r io.Reader) (Diags, error) {
+	ds := []Diag{}
+	if err := json.NewDecoder(r).Decode(&ds); err != nil {
+		return nil, err
+	}
+
+	c := New()
+	for ndx, d := range ds {
+		if d == nil {
+			return nil, fmt.Errorf("element %d: not a diagnostic", ndx)
+		}
+		c.readRecord(d)
+	}
+	return c, nil
+}
+
+// Read a diagnostic group from JSON lines produced by WriteJSONLines. Blank
+// lines are ignored.
+//
+// Fatal diagnostics are recorded without invoking the fatal diagnostic policy.
+func ReadJSONLines(r io.Reader) (Diags, error) {
+	c := New()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		d := &diag{}
+		if err := d.UnmarshalJSON(line); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		c.readRecord(d)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Record a diagnostic read from JSON in the group.
+func (c Diags) readRecord(d Diag) {
+	c.record(d)
+	c.LimitReached = c.LimitReached || d.limit
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bitc-lang/go-compileutil/position"
+)
+
+const jsonLinesString = `{"file":"x","line":1,"column":2,"offset":-1,"kind":"Warning","message":"Danger, Will Robinson!"}
+{"file":"x","line":2,"column":27,"offset":51,"end":{"line":2,"column":30,"offset":54},"kind":"Error","message":"Does not compute!"}
+`
+
+func jsonTestDiags() Diags {
+	diags := New()
+	diags.AddSpan(position.SpanOf(position.OffsetPos("x", 2, 27, 51),
+		position.OffsetPos("x", 2, 30, 54)), Error, "Does not compute!")
+	diags.AddWarn(position.Pos("x", 1, 2), "Danger, Will Robinson!")
+	return diags
+}
+
+func TestJSONLines(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	if err := jsonTestDiags().WriteJSONLines(buf); err != nil {
+		t.Fatalf("Error %v writing JSON lines", err)
+	}
+	if buf.String() != jsonLinesString {
+		t.Fatalf("Unexpected JSON lines output:\n%s", buf.String())
+	}
+
+	diags, err := ReadJSONLines(strings.NewReader(jsonLinesString))
+	if err != nil {
+		t.Fatalf("Error %v reading JSON lines", err)
+	}
+	if !diags.HasError || len(diags.diags) != 2 {
+		t.Fatalf("JSON lines did not decode to the expected diagnostics")
+	}
+	if d := diags.diags[1]; d.Offset() != 51 || d.End.Column() != 30 {
+		t.Fatalf("JSON lines did not decode expected positions: %s", d)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	orig := jsonTestDiags()
+
+	buf := bytes.NewBuffer([]byte{})
+	if err := orig.WriteJSON(buf); err != nil {
+		t.Fatalf("Error %v writing JSON", err)
+	}
+
+	diags, err := ReadJSON(buf)
+	if err != nil {
+		t.Fatalf("Error %v reading JSON", err)
+	}
+
+	if diags.String() != orig.String() {
+		t.Fatalf("JSON round trip does not preserve diagnostics:\n%s", diags)
+	}
+}
+
+func TestJSONLimit(t *testing.T) {
+	orig := New()
+	orig.MaxErrors = 1
+	orig.AddError(position.Pos("x", 1, 1), "First")
+	orig.AddError(position.Pos("x", 2, 1), "Second")
+
+	buf := bytes.NewBuffer([]byte{})
+	if err := orig.WriteJSON(buf); err != nil {
+		t.Fatalf("Error %v writing JSON", err)
+	}
+	diags, err := ReadJSON(buf)
+	if err != nil {
+		t.Fatalf("Error %v reading JSON", err)
+	}
+	if diags.Count(Error) != 1 || !diags.LimitReached || diags.Summary() != orig.Summary() {
+		t.Fatalf("JSON round trip counted the limit diagnostic: %s", diags.Summary())
+	}
+
+	if _, err := ReadJSON(strings.NewReader("[null]")); err == nil {
+		t.Fatalf("A null diagnostic was accepted")
+	}
+}
+
+func TestJSONNotes(t *testing.T) {
+	orig := New()
+	orig.AddError(position.OffsetPos("x", 3, 1, 40), "redefinition of x").
+		AddNote(position.OffsetPos("x", 1, 5, 4), "previous definition was here").
+		AddHelp(nil, "rename one of the definitions")
+
+	buf := bytes.NewBuffer([]byte{})
+	if err := orig.WriteJSONLines(buf); err != nil {
+		t.Fatalf("Error %v writing JSON lines", err)
+	}
+
+	diags, err := ReadJSONLines(buf)
+	if err != nil {
+		t.Fatalf("Error %v reading JSON lines", err)
+	}
+
+	if diags.String() != orig.String() {
+		t.Fatalf("JSON round trip does not preserve notes:\n%s", diags)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// The properties of a diagnostic kind.
+type kindInfo struct {
+	name    string
+	rank    int  // Position in the severity order; lower is more severe.
+	isError bool // Whether diagnostics of this kind are errors.
+}
+
+// Ranks of the predefined kinds. These are spaced so that applications can
+// register kinds that fall between them.
+const (
+	FatalRank   = 0
+	ErrorRank   = 100
+	WarningRank = 200
+	InfoRank    = 300
+	NoteRank    = 400
+	HelpRank    = 500
+	HintRank    = 600
+)
+
+var kindMu sync.RWMutex
+
+// Properties of each kind, indexed by DiagKind.
+var kindTable = []kindInfo{
+	Fatal:    {"Fatal", FatalRank, true},
+	Error:    {"Error", ErrorRank, true},
+	Warning:  {"Warning", WarningRank, false},
+	Info:     {"Info", InfoRank, false},
+	NoteKind: {"Note", NoteRank, false},
+	Help:     {"Help", HelpRank, false},
+	Hint:     {"Hint", HintRank, false},
+}
+
+// Return the properties of kind k, or false if k is not a known kind.
+func (k DiagKind) info() (kindInfo, bool) {
+	kindMu.RLock()
+	defer kindMu.RUnlock()
+
+	if k < 0 || int(k) >= len(kindTable) {
+		return kindInfo{}, false
+	}
+	return kindTable[k], true
+}
+
+// Return the name of the kind, such as "Error". Unknown kinds are named
+// "DiagKind(n)".
+func (k DiagKind) String() string {
+	if ki, ok := k.info(); ok {
+		return ki.name
+	}
+	return "DiagKind(" + strconv.FormatInt(int64(k), 10) + ")"
+}
+
+// Return the position of the kind in the severity order. Kinds of lower rank
+// are more severe, and are sorted first. Unknown kinds rank after all others.
+func (k DiagKind) Rank() int {
+	if ki, ok := k.info(); ok {
+		return ki.rank
+	}
+	return int(^uint(0) >> 1)
+}
+
+// Return true iff diagnostics of this kind are errors. Adding an error sets
+// HasError, so that AsError returns the group, and counts towards MaxErrors.
+func (k DiagKind) IsError() bool {
+	ki, _ := k.info()
+	return ki.isError
+}
+
+// Return true iff k sorts before other: that is, if k is more severe, or if
+// the two are equally severe and k was defined first.
+func (k DiagKind) before(other DiagKind) bool {
+	if rk, ro := k.Rank(), other.Rank(); rk != ro {
+		return rk < ro
+	}
+	return k < other
+}
+
+// Define a new diagnostic kind having the given name, rank in the severity
+// order (see Rank), and error status, returning the new kind:
+//
+//	var Lint = diag.MustRegisterKind("Lint", diag.WarningRank+50, false)
+//
+// Diagnostics of a registered kind are sorted, filtered, and exported
+// according to its rank. Only Fatal diagnostics invoke the OnFatal policy,
+// and only Warning diagnostics set HasWarnings.
+//
+// It is an error to register an empty name, or a name already in use.
+func RegisterKind(name string, rank int, isError bool) (DiagKind, error) {
+	if name == "" {
+		return 0, fmt.Errorf("diagnostic kind has no name")
+	}
+
+	kindMu.Lock()
+	defer kindMu.Unlock()
+
+	for _, ki := range kindTable {
+		if ki.name == name {
+			return 0, fmt.Errorf("diagnostic kind %s registered twice", name)
+		}
+	}
+
+	kindTable = append(kindTable, kindInfo{name, rank, isError})
+	return DiagKind(len(kindTable) - 1), nil
+}
+
+// Define a diagnostic kind as RegisterKind does, panicking on failure.
+// Intended for package-level declarations.
+func MustRegisterKind(name string, rank int, isError bool) DiagKind {
+	k, err := RegisterKind(name, rank, isError)
+	if err != nil {
+		panic(err.Error())
+	}
+	return k
+}
+
+// Return all known kinds, most severe first.
+func Kinds() []DiagKind {
+	kindMu.RLock()
+	kinds := make([]DiagKind, len(kindTable))
+	for ndx := range kindTable {
+		kinds[ndx] = DiagKind(ndx)
+	}
+	kindMu.RUnlock()
+
+	sort.SliceStable(kinds, func(i, j int) bool {
+		return kinds[i].before(kinds[j])
+	})
+	return kinds
+}
+
+// Return the kind having the given name, if there is one.
+func KindNamed(name string) (DiagKind, bool) {
+	kindMu.RLock()
+	defer kindMu.RUnlock()
+
+	for ndx, ki := range kindTable {
+		if ki.name == name {
+			return DiagKind(ndx), true
+		}
+	}
+	return 0, false
+}
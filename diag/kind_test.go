@@ -0,0 +1,110 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bitc-lang/go-compileutil/position"
+)
+
+// Kinds are registered globally, so test kinds are registered once.
+var testLint = MustRegisterKind("Lint", WarningRank+50, false)
+var testBroken = MustRegisterKind("Broken", ErrorRank+50, true)
+
+func TestKindNames(t *testing.T) {
+	for kind, name := range map[DiagKind]string{
+		Fatal:        "Fatal",
+		Error:        "Error",
+		Warning:      "Warning",
+		Info:         "Info",
+		NoteKind:     "Note",
+		Help:         "Help",
+		Hint:         "Hint",
+		testLint:     "Lint",
+		DiagKind(-1): "DiagKind(-1)",
+		DiagKind(99): "DiagKind(99)",
+	} {
+		if kind.String() != name {
+			t.Fatalf("Kind %d is named %q, expected %q", int(kind), kind, name)
+		}
+		if k, ok := KindNamed(name); ok != (kind >= 0 && kind < 99) || (ok && k != kind) {
+			t.Fatalf("KindNamed(%q) returned %v", name, k)
+		}
+	}
+
+	if _, err := RegisterKind("Lint", 0, false); err == nil {
+		t.Fatalf("Duplicate kind name was accepted")
+	}
+	if _, err := RegisterKind("", 0, false); err == nil {
+		t.Fatalf("Empty kind name was accepted")
+	}
+}
+
+func TestKindOrder(t *testing.T) {
+	var order []DiagKind
+	for _, k := range Kinds() {
+		if k == testLint || k == testBroken || k <= Hint {
+			order = append(order, k)
+		}
+	}
+	want := []DiagKind{Fatal, Error, testBroken, Warning, testLint, Info, NoteKind, Help, Hint}
+	if len(order) != len(want) {
+		t.Fatalf("Unexpected kinds %v", order)
+	}
+	for ndx := range want {
+		if order[ndx] != want[ndx] {
+			t.Fatalf("Kinds are ordered %v, expected %v", order, want)
+		}
+	}
+
+	diags := New()
+	diags.Cascade = OnePerPosition
+	at := position.Pos("x", 1, 1)
+	diags.Add(at, Hint, "consider a shorter name")
+	diags.Add(at, testLint, "name is too long")
+	diags.Add(position.Pos("x", 2, 1), Help, "try this")
+	diags.Add(position.Pos("x", 2, 2), NoteKind, "noted")
+
+	expect := "x:1:1: Lint: name is too long\n" +
+		"x:2:1: Help: try this\n" +
+		"x:2:2: Note: noted\n"
+	if diags.String() != expect {
+		t.Fatalf("Unexpected output:\n%s", diags)
+	}
+	if diags.AsError() != nil || diags.HasWarnings {
+		t.Fatalf("Non-error kinds set HasError or HasWarnings")
+	}
+}
+
+func TestCustomErrorKind(t *testing.T) {
+	diags := New()
+	diags.MaxErrors = 2
+	diags.Add(position.Pos("x", 1, 1), testBroken, "first")
+	diags.Add(position.Pos("x", 2, 1), Error, "second")
+	diags.Add(position.Pos("x", 3, 1), testBroken, "third")
+
+	if diags.AsError() == nil || !diags.LimitReached {
+		t.Fatalf("Registered error kind is not treated as an error")
+	}
+	if diags.Summary() != "2 errors generated" {
+		t.Fatalf("Unexpected summary %q", diags.Summary())
+	}
+
+	var buf bytes.Buffer
+	if err := diags.WriteJSONLines(&buf); err != nil {
+		t.Fatalf("Unable to write JSON (error %v)", err)
+	}
+	back, err := ReadJSONLines(&buf)
+	if err != nil || back.All()[0].Kind != testBroken {
+		t.Fatalf("Registered kind did not round trip through JSON (error %v)", err)
+	}
+
+	if sarifLevel(testBroken) != "error" || sarifLevel(testLint) != "warning" ||
+		lspSeverity(Hint) != LSPHint || lspSeverity(NoteKind) != LSPInformation {
+		t.Fatalf("Registered kinds are not mapped by severity")
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// A Message declares a translatable diagnostic message. The English template
+// is written in the syntax of fmt.Sprintf, and is used when no translation is
+// available:
+//
+//	var MsgUndefined = diag.Message{ID: "undefined-name", Template: "undefined: %s"}
+//
+//	diags.AddMessage(pos, diag.Error, MsgUndefined, name)
+//
+// Translated templates may use explicit argument indexes, such as %[2]s, when
+// the target language orders the arguments differently.
+type Message struct {
+	ID       string // Stable identifier used to look up translations.
+	Template string // English template.
+}
+
+// Return the English text of the message for the given arguments.
+func (m Message) Format(args ...any) string {
+	return fmt.Sprintf(m.Template, args...)
+}
+
+// A Catalog supplies translated message templates.
+type Catalog interface {
+	// Return the template translating the message with the given ID into
+	// locale, if there is one.
+	Template(locale, id string) (string, bool)
+}
+
+// A Catalog held in memory, mapping each locale (such as "pt-BR" or "pt") to
+// templates keyed by message ID.
+type MapCatalog map[string]map[string]string
+
+// Implement the Catalog interface.
+func (mc MapCatalog) Template(locale, id string) (string, bool) {
+	tmpl, ok := mc[locale][id]
+	return tmpl, ok
+}
+
+// Return the locales to consult for locale, most specific first. A POSIX
+// locale name such as "pt_BR.UTF-8" is accepted, and yields "pt-BR" and "pt".
+func localeChain(locale string) []string {
+	if i := strings.IndexAny(locale, ".@"); i >= 0 {
+		locale = locale[:i]
+	}
+	locale = strings.ReplaceAll(locale, "_", "-")
+
+	var chain []string
+	for locale != "" {
+		chain = append(chain, locale)
+		i := strings.LastIndex(locale, "-")
+		if i < 0 {
+			break
+		}
+		locale = locale[:i]
+	}
+	return chain
+}
+
+// Return the user's preferred locale for messages, as given by the LC_ALL,
+// LC_MESSAGES, or LANG environment variables, or the empty string if the user
+// has not chosen one.
+func LocaleFromEnv() string {
+	for _, v := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		locale := os.Getenv(v)
+		if locale == "C" || locale == "POSIX" {
+			return ""
+		}
+		if locale != "" {
+			return locale
+		}
+	}
+	return ""
+}
+
+// Return the translation of a message into locale, or false if cat has no
+// template for it.
+func translate(cat Catalog, locale, id string, args []any) (string, bool) {
+	for _, loc := range localeChain(locale) {
+		if tmpl, ok := cat.Template(loc, id); ok {
+			return fmt.Sprintf(tmpl, args...), true
+		}
+	}
+	return "", false
+}
+
+// Return d with its message, and those of its notes, translated into locale
+// using templates from cat. A locale such as "pt-BR" falls back to "pt", and
+// messages that have no translation are left in English. If there is nothing
+// to translate, d itself is returned; otherwise the result is a copy.
+func Localize(d Diag, cat Catalog, locale string) Diag {
+	if cat == nil || locale == "" {
+		return d
+	}
+
+	var out *diag
+	if d.MessageID != "" {
+		if msg, ok := translate(cat, locale, d.MessageID, d.Args); ok {
+			cp := *d
+			cp.Message = msg
+			out = &cp
+		}
+	}
+
+	copied := false // Whether out.Notes is a copy of d.Notes.
+	for ndx, n := range d.Notes {
+		if n.MessageID == "" {
+			continue
+		}
+		msg, ok := translate(cat, locale, n.MessageID, n.Args)
+		if !ok {
+			continue
+		}
+		if out == nil {
+			cp := *d
+			out = &cp
+		}
+		if !copied {
+			out.Notes = append([]Note{}, d.Notes...)
+			copied = true
+		}
+		out.Notes[ndx].Message = msg
+	}
+
+	if out == nil {
+		return d
+	}
+	return out
+}
+
+// Add a diagnostic with the specified location and severity whose message is
+// m formatted with args. The diagnostic's Message is the English text, and is
+// translated when printed by a group having a Catalog and Locale.
+func (c Diags) AddMessage(where Position, kind DiagKind, m Message, args ...any) Diags {
+	return c.add(&diag{Pos: where, Kind: kind,
+		Message: m.Format(args...), MessageID: m.ID, Args: args})
+}
+
+// Attach a note at the specified location (which may be nil) whose message is
+// m formatted with args.
+func (d Diag) AddNoteMessage(where Position, m Message, args ...any) Diag {
+	d.Notes = append(d.Notes, Note{Pos: where, Label: NoteLabel,
+		Message: m.Format(args...), MessageID: m.ID, Args: args})
+	return d
+}
+
+// Attach a note whose message is m formatted with args to the most recently
+// added diagnostic in the group, as AddNote does.
+func (c Diags) AddNoteMessage(where Position, m Message, args ...any) Diags {
+	if c.last != nil {
+		c.last.AddNoteMessage(where, m, args...)
+	}
+	return c
+}
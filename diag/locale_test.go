@@ -0,0 +1,82 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bitc-lang/go-compileutil/position"
+)
+
+var msgUndefined = Message{ID: "undefined", Template: "undefined: %s"}
+var msgMismatch = Message{ID: "mismatch", Template: "cannot use %s as %s"}
+var msgDeclared = Message{ID: "declared", Template: "%s declared here"}
+
+var testCatalog = MapCatalog{
+	"pt": {
+		"undefined": "indefinido: %s",
+		"mismatch":  "não é possível usar %s como %s",
+		"declared":  "%s declarado aqui",
+	},
+	"pt-BR": {
+		"mismatch": "impossível usar %[1]s como %[2]s",
+	},
+}
+
+func TestLocalize(t *testing.T) {
+	diags := New()
+	diags.AddMessage(position.Pos("x", 1, 1), Error, msgUndefined, "foo").
+		AddNoteMessage(position.Pos("x", 3, 1), msgDeclared, "bar")
+	diags.AddMessage(position.Pos("x", 2, 1), Error, msgMismatch, "int", "string")
+	diags.AddWarn(position.Pos("x", 4, 1), "plain message")
+
+	english := "x:1:1: Error: undefined: foo\n" +
+		"x:3:1: note: bar declared here\n" +
+		"x:2:1: Error: cannot use int as string\n" +
+		"x:4:1: Warning: plain message\n"
+	if diags.String() != english {
+		t.Fatalf("Untranslated output not as expected:\n%s", diags)
+	}
+
+	diags.Catalog = testCatalog
+	diags.Locale = "pt_BR.UTF-8"
+	translated := "x:1:1: Error: indefinido: foo\n" +
+		"x:3:1: note: bar declarado aqui\n" +
+		"x:2:1: Error: impossível usar int como string\n" +
+		"x:4:1: Warning: plain message\n"
+	if diags.String() != translated {
+		t.Fatalf("Translated output not as expected:\n%s", diags)
+	}
+
+	var sb strings.Builder
+	if err := diags.Print(&sb); err != nil || sb.String() != translated {
+		t.Fatalf("Printed output not translated:\n%s", sb.String())
+	}
+
+	diags.Locale = "fr"
+	if diags.String() != english {
+		t.Fatalf("Missing locale did not fall back to English:\n%s", diags)
+	}
+
+	// Localizing must not alter the recorded diagnostics.
+	if d := diags.All()[0]; d.Message != "undefined: foo" || d.Notes[0].Message != "bar declared here" {
+		t.Fatalf("Localization modified the recorded diagnostic")
+	}
+}
+
+func TestLocaleChain(t *testing.T) {
+	for locale, want := range map[string][]string{
+		"pt_BR.UTF-8": {"pt-BR", "pt"},
+		"zh-Hant-TW":  {"zh-Hant-TW", "zh-Hant", "zh"},
+		"de_DE@euro":  {"de-DE", "de"},
+		"":            nil,
+	} {
+		if got := localeChain(locale); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Locale chain of %q is %v, expected %v", locale, got, want)
+		}
+	}
+}
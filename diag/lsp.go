@@ -0,0 +1,188 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+
+	"github.com/bitc-lang/go-compileutil/reader"
+)
+
+// Options controlling conversion to Language Server Protocol payloads.
+type LSPOptions struct {
+	ToolName string // Reported as the source of each diagnostic, if non-empty.
+
+	// Return the reader for the named input unit, or nil if the source text is
+	// not available. Source text is needed to compute UTF-16 columns; without
+	// it, byte columns are reported.
+	Source func(filename string) reader.Reader
+	// Return the document URI for the named input unit. If URI is nil,
+	// file names are converted to absolute "file:" URIs.
+	URI func(filename string) string
+}
+
+// The severity of an LSP diagnostic.
+type LSPSeverity int
+
+const (
+	LSPError       LSPSeverity = 1
+	LSPWarning     LSPSeverity = 2
+	LSPInformation LSPSeverity = 3
+	LSPHint        LSPSeverity = 4
+)
+
+// The subset of the LSP object model used to publish diagnostics. Field names
+// and JSON encodings follow the LSP 3.17 specification.
+
+// A zero-based line and UTF-16 character offset within a document.
+type LSPPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type LSPRange struct {
+	Start LSPPosition `json:"start"`
+	End   LSPPosition `json:"end"`
+}
+
+type LSPLocation struct {
+	URI   string   `json:"uri"`
+	Range LSPRange `json:"range"`
+}
+
+type LSPRelatedInformation struct {
+	Location LSPLocation `json:"location"`
+	Message  string      `json:"message"`
+}
+
+type LSPDiagnostic struct {
+	Range              LSPRange                `json:"range"`
+	Severity           LSPSeverity             `json:"severity,omitempty"`
+	Code               string                  `json:"code,omitempty"`
+	Source             string                  `json:"source,omitempty"`
+	Message            string                  `json:"message"`
+	RelatedInformation []LSPRelatedInformation `json:"relatedInformation,omitempty"`
+}
+
+// The parameters of a textDocument/publishDiagnostics notification.
+type PublishDiagnosticsParams struct {
+	URI         string          `json:"uri"`
+	Diagnostics []LSPDiagnostic `json:"diagnostics"`
+}
+
+// Return the LSP severity corresponding to a diagnostic kind.
+func lspSeverity(k DiagKind) LSPSeverity {
+	switch {
+	case k.IsError():
+		return LSPError
+	case k.Rank() < InfoRank:
+		return LSPWarning
+	case k.Rank() < HintRank:
+		return LSPInformation
+	default:
+		return LSPHint
+	}
+}
+
+// Return the document URI for the named input unit.
+func (opts *LSPOptions) uri(filename string) string {
+	if opts.URI != nil {
+		return opts.URI(filename)
+	}
+	if abs, err := filepath.Abs(filename); err == nil {
+		filename = abs
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(filename)}).String()
+}
+
+// Return the LSP position of pos, which must not be nil.
+func (opts *LSPOptions) position(pos Position) LSPPosition {
+	raw := pos.Raw()
+	if opts.Source != nil {
+		if r := opts.Source(raw.Filename()); r != nil {
+			if o, l, ok := lineOf(r, raw); ok {
+				return LSPPosition{Line: l - 1, Character: reader.UTF16Column(r, o, false) - 1}
+			}
+		}
+	}
+
+	lp := LSPPosition{Line: raw.Line() - 1, Character: raw.Column() - 1}
+	if lp.Line < 0 || lp.Character < 0 {
+		return LSPPosition{}
+	}
+	return lp
+}
+
+// Return the LSP location of the span [start, end), or of start alone if end
+// is nil.
+func (opts *LSPOptions) location(start, end Position) LSPLocation {
+	rng := LSPRange{Start: opts.position(start)}
+	rng.End = rng.Start
+	if end != nil {
+		rng.End = opts.position(end)
+	}
+	return LSPLocation{URI: opts.uri(start.Raw().Filename()), Range: rng}
+}
+
+// Return the diagnostics of the group as LSP publishDiagnostics parameters,
+// one per document, ordered by URI.
+//
+// Diagnostics are located by their raw (line directive unadjusted) positions.
+// Messages are localized using the group's Catalog and Locale. Notes with
+// positions become related information, while other notes are appended to the
+// message text. Diagnostics without a position cannot be published, and are
+// omitted.
+func (c Diags) PublishDiagnostics(opts LSPOptions) []PublishDiagnosticsParams {
+	byURI := map[string]*PublishDiagnosticsParams{}
+
+	for _, d := range c.sorted() {
+		if d.Pos == nil {
+			continue
+		}
+		d = Localize(d, c.Catalog, c.Locale)
+
+		loc := opts.location(d.Pos, d.End)
+		ld := LSPDiagnostic{
+			Range:    loc.Range,
+			Severity: lspSeverity(d.Kind),
+			Code:     d.Code,
+			Source:   opts.ToolName,
+			Message:  d.Message,
+		}
+
+		for _, n := range d.Notes {
+			if n.Pos == nil {
+				ld.Message += "\n" + n.String()
+				continue
+			}
+			ld.RelatedInformation = append(ld.RelatedInformation, LSPRelatedInformation{
+				Location: opts.location(n.Pos, n.End),
+				Message:  fmt.Sprintf("%s: %s", n.Label, n.Message),
+			})
+		}
+
+		params, ok := byURI[loc.URI]
+		if !ok {
+			params = &PublishDiagnosticsParams{URI: loc.URI, Diagnostics: []LSPDiagnostic{}}
+			byURI[loc.URI] = params
+		}
+		params.Diagnostics = append(params.Diagnostics, ld)
+	}
+
+	uris := make([]string, 0, len(byURI))
+	for uri := range byURI {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+
+	result := make([]PublishDiagnosticsParams, 0, len(uris))
+	for _, uri := range uris {
+		result = append(result, *byURI[uri])
+	}
+	return result
+}
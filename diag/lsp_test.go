@@ -0,0 +1,62 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/bitc-lang/go-compileutil/position"
+	"github.com/bitc-lang/go-compileutil/reader"
+)
+
+func TestPublishDiagnostics(t *testing.T) {
+	src := "let s = \"😀\" + y\nlet y = 1\n"
+	r, _ := reader.OnString(src)
+	name := r.Filename(0, false)
+	yAt := strings.Index(src, "y\n")
+
+	diags := New()
+	diags.AddSpan(position.SpanOf(
+		position.OffsetPos(name, 1, yAt+1, yAt),
+		position.OffsetPos(name, 1, yAt+2, yAt+1)), Error, "use of y before definition")
+	diags.AddNote(position.OffsetPos(name, 2, 5, len("let s = \"😀\" + y\nlet ")), "y defined here")
+	diags.AddHelp(nil, "move the definition earlier")
+	diags.AddCoded(position.Pos("other", 3, 2), &Descriptor{Code: "W1", Kind: Warning}, "shadowed")
+	diags.AddInfo(nil, "no position")
+
+	params := diags.PublishDiagnostics(LSPOptions{
+		ToolName: "bitcc",
+		Source: func(filename string) reader.Reader {
+			if filename == name {
+				return r
+			}
+			return nil
+		},
+		URI: func(filename string) string { return "mem:///" + strings.Trim(filename, "<>") },
+	})
+
+	out, err := json.Marshal(params)
+	if err != nil {
+		t.Fatalf("Unable to marshal LSP parameters (error %v)", err)
+	}
+
+	// The emoji is four bytes but two UTF-16 units, so y is at character 15
+	// rather than byte column 17.
+	expect := `[{"uri":"mem:///other","diagnostics":[{` +
+		`"range":{"start":{"line":2,"character":1},"end":{"line":2,"character":1}},` +
+		`"severity":2,"code":"W1","source":"bitcc","message":"shadowed"}]},` +
+		`{"uri":"mem:///string","diagnostics":[{` +
+		`"range":{"start":{"line":0,"character":15},"end":{"line":0,"character":16}},` +
+		`"severity":1,"source":"bitcc",` +
+		`"message":"use of y before definition\nhelp: move the definition earlier",` +
+		`"relatedInformation":[{"location":{"uri":"mem:///string",` +
+		`"range":{"start":{"line":1,"character":4},"end":{"line":1,"character":4}}},` +
+		`"message":"note: y defined here"}]}]}]`
+	if string(out) != expect {
+		t.Fatalf("Unexpected LSP output:\n%s", out)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+// A point in the history of a diagnostic group, as returned by Diags.Mark.
+type Mark struct {
+	depth        int // Nesting depth of the mark, starting at 1.
+	count        int // Number of diagnostics recorded when the mark was made.
+	hasError     bool
+	hasWarnings  bool
+	limitReached bool
+	suppressed   int
+	last         Diag
+	lastNotes    int // Number of notes of last when the mark was made.
+	lastSuggests int // Number of suggestions of last when the mark was made.
+}
+
+// Mark the current state of the group, so that diagnostics added by a
+// speculative parse can later be discarded by Rollback or kept by Commit:
+//
+//	m := diags.Mark()
+//	if ok := tryParse(diags); ok {
+//		diags.Commit(m)
+//	} else {
+//		diags.Rollback(m)
+//	}
+//
+// Marks nest. Each mark must be resolved by exactly one call to Rollback or
+// Commit, and marks must be resolved in the reverse of the order in which they
+// were made.
+func (c Diags) Mark() Mark {
+	c.marks++
+
+	m := Mark{
+		depth:        c.marks,
+		count:        len(c.diags),
+		hasError:     c.HasError,
+		hasWarnings:  c.HasWarnings,
+		limitReached: c.LimitReached,
+		suppressed:   c.suppressed,
+		last:         c.last,
+	}
+	if c.last != nil {
+		m.lastNotes = len(c.last.Notes)
+		m.lastSuggests = len(c.last.Suggestions)
+	}
+	return m
+}
+
+// Panic unless m is the innermost unresolved mark of the group.
+func (c Diags) checkMark(m Mark) {
+	if m.depth != c.marks || m.depth == 0 || m.count > len(c.diags) {
+		panic("diag: mark resolved out of order")
+	}
+}
+
+// Discard all diagnostics added since m was made, including any notes and
+// suggestions attached to the diagnostic that was most recent at that time,
+// and resolve m.
+//
+// Rollback does not undo the use of in-source suppressions by discarded
+// diagnostics.
+func (c Diags) Rollback(m Mark) Diags {
+	c.checkMark(m)
+
+	for _, d := range c.diags[m.count:] {
+		delete(c.seen, d.key())
+		if !d.limit {
+			c.counts[d.Kind]--
+		}
+	}
+	c.diags = c.diags[:m.count]
+
+	c.HasError = m.hasError
+	c.HasWarnings = m.hasWarnings
+	c.LimitReached = m.limitReached
+	c.suppressed = m.suppressed
+	c.last = m.last
+	if m.last != nil {
+		m.last.Notes = m.last.Notes[:m.lastNotes]
+		m.last.Suggestions = m.last.Suggestions[:m.lastSuggests]
+	}
+
+	c.marks--
+	return c
+}
+
+// Keep all diagnostics added since m was made, and resolve m. If m is nested
+// within another mark, the diagnostics can still be discarded by rolling back
+// the enclosing mark.
+func (c Diags) Commit(m Mark) Diags {
+	c.checkMark(m)
+	c.marks--
+	c.emit(false)
+	return c
+}
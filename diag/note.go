@@ -0,0 +1,82 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"fmt"
+)
+
+// Labels distinguishing the kinds of note that can be attached to a diagnostic.
+const (
+	NoteLabel = "note"
+	HelpLabel = "help"
+)
+
+// A secondary message attached to a diagnostic, such as "previous definition
+// was here". Notes are sorted and printed together with their parent
+// diagnostic.
+type Note struct {
+	Pos     Position // Position of the note, or nil if there is none.
+	End     Position // End of the noted span, or nil for a single position.
+	Label   string   // NoteLabel or HelpLabel.
+	Message string   // Message text, in English.
+
+	MessageID string // Identifier of a translatable message (see AddNoteMessage).
+	Args      []any  // Arguments of the translatable message.
+}
+
+// Return a string representing the note.
+func (n Note) String() string {
+	return n.headline(palette{})
+}
+
+func (n Note) headline(p palette) string {
+	label := p.paint(ansiCyan, n.Label)
+	if n.Pos == nil {
+		return fmt.Sprintf("%s: %s", label, n.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", p.paint(ansiBold, n.Pos.String()), label, n.Message)
+}
+
+// Attach a note at the specified location (which may be nil) to the
+// diagnostic.
+func (d Diag) AddNote(where Position, msg string) Diag {
+	d.Notes = append(d.Notes, Note{Pos: where, Label: NoteLabel, Message: msg})
+	return d
+}
+
+// Attach a help entry at the specified location (which may be nil) to the
+// diagnostic.
+func (d Diag) AddHelp(where Position, msg string) Diag {
+	d.Notes = append(d.Notes, Note{Pos: where, Label: HelpLabel, Message: msg})
+	return d
+}
+
+// Attach a note at the specified location (which may be nil) to the most
+// recently added diagnostic in the group, so that
+//
+//	diags.AddError(here, "redefinition of x").
+//		AddNote(there, "previous definition was here")
+//
+// reports the two together.
+//
+// Does nothing if no diagnostic has been added to the group.
+func (c Diags) AddNote(where Position, msg string) Diags {
+	if c.last != nil {
+		c.last.AddNote(where, msg)
+	}
+	return c
+}
+
+// Attach a help entry at the specified location (which may be nil) to the most
+// recently added diagnostic in the group.
+//
+// Does nothing if no diagnostic has been added to the group.
+func (c Diags) AddHelp(where Position, msg string) Diags {
+	if c.last != nil {
+		c.last.AddHelp(where, msg)
+	}
+	return c
+}
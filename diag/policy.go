@@ -0,0 +1,80 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+// A Policy adjusts the severity of diagnostics as they are added to a group,
+// implementing controls such as -Werror and -Wno-unused-variable.
+//
+// Rules are keyed by diagnostic code or by category (see Descriptor). A rule
+// for a code takes precedence over a rule for its category, and a later rule
+// for the same key replaces an earlier one. Fatal diagnostics are never
+// adjusted.
+type Policy struct {
+	// Promote all warnings to errors, after applying any rules.
+	WarningsAsErrors bool
+
+	rules map[string]policyRule
+}
+
+type policyRule struct {
+	suppress bool
+	kind     DiagKind
+}
+
+// Return a fresh policy having no rules.
+func NewPolicy() *Policy {
+	return &Policy{
+		WarningsAsErrors: false,
+		rules:            map[string]policyRule{},
+	}
+}
+
+// Record diagnostics having the given code or category with severity kind.
+func (p *Policy) SetSeverity(key string, kind DiagKind) *Policy {
+	p.rules[key] = policyRule{kind: kind}
+	return p
+}
+
+// Record diagnostics having the given code or category as errors.
+func (p *Policy) Promote(key string) *Policy {
+	return p.SetSeverity(key, Error)
+}
+
+// Record diagnostics having the given code or category as warnings.
+func (p *Policy) Demote(key string) *Policy {
+	return p.SetSeverity(key, Warning)
+}
+
+// Discard diagnostics having the given code or category.
+func (p *Policy) Suppress(key string) *Policy {
+	p.rules[key] = policyRule{suppress: true}
+	return p
+}
+
+// Return the severity with which d should be recorded, and false if d should
+// be discarded instead.
+func (p *Policy) apply(d Diag) (DiagKind, bool) {
+	if d.Kind == Fatal {
+		return d.Kind, true
+	}
+
+	kind := d.Kind
+	rule, ok := p.rules[d.Code]
+	if !ok || d.Code == "" {
+		rule, ok = p.rules[d.Category]
+		ok = ok && d.Category != ""
+	}
+	if ok {
+		if rule.suppress {
+			return kind, false
+		}
+		kind = rule.kind
+	}
+
+	if kind == Warning && p.WarningsAsErrors {
+		kind = Error
+	}
+	return kind, true
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"testing"
+
+	"github.com/bitc-lang/go-compileutil/position"
+)
+
+const policyString = `x:1:1: Error[W0001]: shadowed name
+x:3:1: Warning[E0002]: deprecated syntax
+`
+
+func TestPolicy(t *testing.T) {
+	codes := NewRegistry()
+	shadow := codes.MustRegister(Descriptor{Code: "W0001", Kind: Warning, Category: "shadow"})
+	syntax := codes.MustRegister(Descriptor{Code: "E0002", Kind: Error, Category: "syntax"})
+	unused := codes.MustRegister(Descriptor{Code: "W0042", Kind: Warning, Category: "unused"})
+
+	diags := New()
+	diags.Policy = NewPolicy().Promote("shadow").Demote("E0002").Suppress("unused")
+
+	diags.AddCoded(position.Pos("x", 1, 1), shadow, "shadowed name")
+	diags.AddCoded(position.Pos("x", 2, 1), unused, "unused variable").
+		AddNote(position.Pos("x", 1, 1), "declared here")
+	diags.AddCoded(position.Pos("x", 3, 1), syntax, "deprecated syntax")
+
+	if diags.String() != policyString {
+		t.Fatalf("Unexpected diagnostics under policy:\n%s", diags)
+	}
+	if diags.Suppressed() != 1 {
+		t.Fatalf("Expected one suppressed diagnostic, got %d", diags.Suppressed())
+	}
+
+	diags = New()
+	diags.Policy = NewPolicy()
+	diags.Policy.WarningsAsErrors = true
+	diags.AddWarn(position.Pos("x", 1, 2), "Danger, Will Robinson!")
+	if diags.AsError() == nil {
+		t.Fatalf("Warnings should be errors under WarningsAsErrors")
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+// Return the diagnostics reported by the group, in the order in which they are
+// printed: sorted by the active sorting algorithm, with cascaded diagnostics
+// removed according to the Cascade mode.
+//
+// The returned slice is a fresh copy, and may be modified by the caller.
+func (c Diags) All() []Diag {
+	return c.sorted()
+}
+
+// Return the number of diagnostics reported by the group. This is the length
+// of the slice returned by All.
+func (c Diags) Len() int {
+	if c.Cascade == KeepAll {
+		return len(c.diags)
+	}
+	return len(c.sorted())
+}
+
+// Return the reported diagnostics for which keep returns true, in the order
+// returned by All.
+func (c Diags) Filter(keep func(Diag) bool) []Diag {
+	kept := []Diag{}
+	for _, d := range c.sorted() {
+		if keep(d) {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
+
+// Return the reported diagnostics whose (line directive adjusted) position
+// names the given file, in the order returned by All.
+func (c Diags) ByFile(filename string) []Diag {
+	return c.Filter(func(d Diag) bool {
+		return d.Pos != nil && d.Filename() == filename
+	})
+}
+
+// Return the reported diagnostics of the given kind, in the order returned by
+// All.
+func (c Diags) ByKind(kind DiagKind) []Diag {
+	return c.Filter(func(d Diag) bool {
+		return d.Kind == kind
+	})
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"testing"
+
+	"github.com/bitc-lang/go-compileutil/position"
+)
+
+func TestQuery(t *testing.T) {
+	diags := New()
+	diags.AddError(position.Pos("y", 2, 27), "Does not compute!")
+	diags.AddWarn(position.Pos("x", 1, 2), "Danger, Will Robinson!")
+	diags.AddInfo(position.Pos("x", 1, 2), "That's great information!")
+	diags.AddInfo(nil, "No position at all")
+
+	all := diags.All()
+	if diags.Len() != 4 || len(all) != 4 {
+		t.Fatalf("Unexpected number of diagnostics %d", diags.Len())
+	}
+	if all[0].Message != "Danger, Will Robinson!" || all[3].Position() != nil {
+		t.Fatalf("All() did not return sorted diagnostics")
+	}
+
+	if ds := diags.ByFile("x"); len(ds) != 2 || ds[1].Kind != Info {
+		t.Fatalf("Unexpected diagnostics for file x: %v", ds)
+	}
+	if ds := diags.ByKind(Info); len(ds) != 2 || ds[1].Message != "No position at all" {
+		t.Fatalf("Unexpected Info diagnostics: %v", ds)
+	}
+	if ds := diags.Filter(func(d Diag) bool { return d.Line() > 1 }); len(ds) != 1 {
+		t.Fatalf("Unexpected filtered diagnostics: %v", ds)
+	}
+
+	diags.Cascade = OnePerPosition
+	if diags.Len() != 3 {
+		t.Fatalf("Len does not reflect cascade mode")
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A Descriptor declares a diagnostic code, giving its default severity and
+// documentation.
+//
+// Because Fatal is the zero value of DiagKind, a Kind of Fatal cannot be told
+// apart from an unset Kind, and is treated as Error. Fatal codes must set
+// Fatal instead. This applies both to registered descriptors and to those
+// passed directly to Diags.AddCoded.
+type Descriptor struct {
+	Code        string   // Stable identifier, such as "E0123".
+	Category    string   // Optional category name, such as "unused-variable".
+	Kind        DiagKind // Default severity of diagnostics issued with this code.
+	Fatal       bool     // Issue diagnostics with this code as Fatal, ignoring Kind.
+	Summary     string   // One line description of the diagnostic.
+	Explanation string   // Long form explanation, as returned by Explain.
+}
+
+// Return the severity of diagnostics issued with the descriptor's code.
+func (desc *Descriptor) kind() DiagKind {
+	switch {
+	case desc.Fatal:
+		return Fatal
+	case desc.Kind == Fatal:
+		return Error
+	default:
+		return desc.Kind
+	}
+}
+
+// A Registry records the diagnostic codes declared by an application.
+//
+// A compiler typically declares its codes in package-level variables:
+//
+//	var codes = diag.NewRegistry()
+//
+//	var ErrUndefined = codes.MustRegister(diag.Descriptor{
+//		Code:    "E0123",
+//		Kind:    diag.Error,
+//		Summary: "use of an undefined name",
+//	})
+//
+// and issues diagnostics with Diags.AddCoded. Registries are safe for
+// concurrent use.
+type Registry struct {
+	mu    sync.Mutex
+	codes map[string]*Descriptor
+}
+
+// Return a fresh, empty registry.
+func NewRegistry() *Registry {
+	return &Registry{codes: map[string]*Descriptor{}}
+}
+
+// Declare a diagnostic code, returning the registered descriptor. The Kind of
+// the registered descriptor is Fatal if desc.Fatal is set, and Error if
+// desc.Kind is unset.
+//
+// It is an error to register an empty code, or a code that has already been
+// registered.
+func (r *Registry) Register(desc Descriptor) (*Descriptor, error) {
+	if desc.Code == "" {
+		return nil, fmt.Errorf("diagnostic descriptor has no code")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.codes[desc.Code]; ok {
+		return nil, fmt.Errorf("diagnostic code %s registered twice", desc.Code)
+	}
+
+	desc.Kind = desc.kind()
+	d := &desc
+	r.codes[desc.Code] = d
+	return d, nil
+}
+
+// Declare a diagnostic code as Register does, panicking on failure. Intended
+// for package-level declarations.
+func (r *Registry) MustRegister(desc Descriptor) *Descriptor {
+	d, err := r.Register(desc)
+	if err != nil {
+		panic(err.Error())
+	}
+	return d
+}
+
+// Return the descriptor registered for code, if any.
+func (r *Registry) Lookup(code string) (*Descriptor, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d, ok := r.codes[code]
+	return d, ok
+}
+
+// Return the registered codes in sorted order.
+func (r *Registry) Codes() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	codes := make([]string, 0, len(r.codes))
+	for code := range r.codes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Return the long form documentation for code, in the manner of
+// "rustc --explain":
+//
+//	E0123: use of an undefined name
+//
+//	<explanation>
+func (r *Registry) Explain(code string) (string, error) {
+	d, ok := r.Lookup(code)
+	if !ok {
+		return "", fmt.Errorf("no diagnostic with code %s", code)
+	}
+
+	s := fmt.Sprintf("%s: %s\n", d.Code, d.Summary)
+	if d.Explanation != "" {
+		s = fmt.Sprintf("%s\n%s\n", s, strings.TrimRight(d.Explanation, "\n"))
+	}
+	return s, nil
+}
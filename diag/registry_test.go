@@ -0,0 +1,81 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"testing"
+
+	"github.com/bitc-lang/go-compileutil/position"
+)
+
+const explainString = `E0123: use of an undefined name
+
+A name was used that is not bound in any enclosing scope.
+`
+
+func TestRegistry(t *testing.T) {
+	codes := NewRegistry()
+
+	undefined := codes.MustRegister(Descriptor{
+		Code:        "E0123",
+		Kind:        Error,
+		Summary:     "use of an undefined name",
+		Explanation: "A name was used that is not bound in any enclosing scope.\n",
+	})
+	codes.MustRegister(Descriptor{Code: "W0042", Kind: Warning, Summary: "unused variable"})
+
+	if _, err := codes.Register(Descriptor{Code: "E0123", Kind: Warning}); err == nil {
+		t.Fatalf("Duplicate registration should fail")
+	}
+
+	if s, err := codes.Explain("E0123"); err != nil || s != explainString {
+		t.Fatalf("Unexpected explanation (error %v):\n%s", err, s)
+	}
+	if _, err := codes.Explain("E9999"); err == nil {
+		t.Fatalf("Explaining an unknown code should fail")
+	}
+	if c := codes.Codes(); len(c) != 2 || c[0] != "E0123" || c[1] != "W0042" {
+		t.Fatalf("Unexpected registered codes %v", c)
+	}
+
+	diags := New()
+	diags.AddCoded(position.Pos("x", 2, 27), undefined, "undefined: foo")
+	if !diags.HasError {
+		t.Fatalf("Coded diagnostic did not take its default severity")
+	}
+	if diags.String() != "x:2:27: Error[E0123]: undefined: foo\n" {
+		t.Fatalf("Unexpected coded diagnostic string %q", diags.String())
+	}
+}
+
+func TestRegistryKinds(t *testing.T) {
+	codes := NewRegistry()
+	unset := codes.MustRegister(Descriptor{Code: "E1", Summary: "kind not given"})
+	fatal := codes.MustRegister(Descriptor{Code: "F1", Fatal: true, Summary: "cannot continue"})
+
+	if unset.Kind != Error || fatal.Kind != Fatal {
+		t.Fatalf("Registered kinds are %v and %v, expected Error and Fatal", unset.Kind, fatal.Kind)
+	}
+
+	diags := New()
+	diags.AddCoded(position.Pos("x", 1, 1), unset, "not fatal")
+	if !diags.HasError || diags.Count(Error) != 1 {
+		t.Fatalf("Code with unset kind was not issued as an error")
+	}
+	if fe := CatchFatal(func() { diags.AddCoded(position.Pos("x", 2, 1), fatal, "fatal") }); fe == nil {
+		t.Fatalf("Fatal code did not invoke the fatal policy")
+	}
+
+	// Unregistered descriptors are issued with the same severity.
+	diags.AddCoded(position.Pos("x", 3, 1), &Descriptor{Code: "E2"}, "not fatal")
+	diags.AddCoded(position.Pos("x", 4, 1), &Descriptor{Code: "E3", Kind: Fatal}, "not fatal")
+	if diags.Count(Error) != 3 {
+		t.Fatalf("Unregistered code with unset kind was not issued as an error")
+	}
+	desc := &Descriptor{Code: "F2", Fatal: true}
+	if fe := CatchFatal(func() { diags.AddCoded(position.Pos("x", 5, 1), desc, "fatal") }); fe == nil {
+		t.Fatalf("Unregistered fatal code did not invoke the fatal policy")
+	}
+}
@@ -0,0 +1,364 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bitc-lang/go-compileutil/reader"
+)
+
+// A Renderer writes diagnostics together with an excerpt of the source lines
+// they refer to, in the style of clang and rustc:
+//
+//	x.bit:2:9: Error: undefined: foo
+//	 2 | let x = foo;
+//	   |         ^
+//
+// Source text is obtained through the Source function using the raw (line
+// directive unadjusted) file name of each diagnostic. Diagnostics whose source
+// is unavailable are printed without an excerpt.
+type Renderer struct {
+	// Return the reader for the named input unit, or nil if the source text is
+	// not available.
+	Source func(filename string) reader.Reader
+	// Emit ANSI color escapes for severity labels, locations and carets.
+	Color bool
+	// Catalog of translated messages and the locale in which to render them
+	// (see Localize). If Catalog is nil, Render uses those of the group.
+	Catalog Catalog
+	Locale  string
+}
+
+// Return a fresh plain-text Renderer that draws source excerpts from the
+// provided readers.
+func NewRenderer(readers ...reader.Reader) *Renderer {
+	byName := map[string]reader.Reader{}
+	for _, r := range readers {
+		byName[r.Filename(0, false)] = r
+	}
+
+	return &Renderer{
+		Source: func(filename string) reader.Reader {
+			return byName[filename]
+		},
+		Color: false,
+	}
+}
+
+// Return true iff output written to w should be colored.
+//
+// Following common convention, a non-empty NO_COLOR environment variable
+// disables color, and a CLICOLOR_FORCE environment variable other than "0"
+// enables it. Otherwise, output is colored only when w is an *os.File that
+// refers to a terminal, and TERM is not "dumb".
+func ColorEnabled(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if force, ok := os.LookupEnv("CLICOLOR_FORCE"); ok && force != "0" {
+		return true
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// Write the diagnostics of the group to w, with source excerpts drawn from the
+// provided readers. Output is colored if ColorEnabled(w) is true, and plain
+// otherwise.
+func (c Diags) Print(w io.Writer, readers ...reader.Reader) error {
+	rn := NewRenderer(readers...)
+	rn.Color = ColorEnabled(w)
+	rn.Catalog, rn.Locale = c.Catalog, c.Locale
+	return rn.Render(w, c)
+}
+
+// ANSI escape sequences used when rendering in color.
+const (
+	ansiReset   = "\x1b[0m"
+	ansiBold    = "\x1b[1m"
+	ansiRed     = "\x1b[1;31m"
+	ansiGreen   = "\x1b[1;32m"
+	ansiMagenta = "\x1b[1;35m"
+	ansiBlue    = "\x1b[1;34m"
+	ansiCyan    = "\x1b[1;36m"
+)
+
+// A palette decides whether and how text is colored.
+type palette struct {
+	color bool
+}
+
+func (p palette) paint(esc string, s string) string {
+	if !p.color || s == "" {
+		return s
+	}
+	return esc + s + ansiReset
+}
+
+func (p palette) kind(k DiagKind) string {
+	switch {
+	case k.IsError():
+		return p.paint(ansiRed, k.String())
+	case k.Rank() < InfoRank:
+		return p.paint(ansiMagenta, k.String())
+	default:
+		return p.paint(ansiCyan, k.String())
+	}
+}
+
+// Return the first line of a diagnostic, giving its position, severity, code
+// and message.
+func (d Diag) headline(p palette) string {
+	kind := p.kind(d.Kind)
+	if d.Code != "" {
+		kind = fmt.Sprintf("%s[%s]", kind, d.Code)
+	}
+	if d.Pos == nil {
+		return fmt.Sprintf("%s: %s", kind, p.paint(ansiBold, d.Message))
+	}
+	return fmt.Sprintf("%s: %s: %s",
+		p.paint(ansiBold, d.posString()), kind, p.paint(ansiBold, d.Message))
+}
+
+// Write all diagnostics in the group to w, sorted by the active sorting
+// algorithm.
+func (rn *Renderer) Render(w io.Writer, c Diags) error {
+	if rn.Catalog == nil && c.Catalog != nil {
+		withGroup := *rn
+		withGroup.Catalog, withGroup.Locale = c.Catalog, c.Locale
+		rn = &withGroup
+	}
+
+	for _, d := range c.sorted() {
+		if err := rn.RenderDiag(w, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write a single diagnostic to w, followed by an excerpt of its source text if
+// that is available, and then by each of its notes in the same form.
+func (rn *Renderer) RenderDiag(w io.Writer, d Diag) error {
+	p := palette{color: rn.Color}
+	d = Localize(d, rn.Catalog, rn.Locale)
+
+	if _, err := fmt.Fprintln(w, d.headline(p)); err != nil {
+		return err
+	}
+	if err := rn.excerpt(w, p, d.Raw(), d.End); err != nil {
+		return err
+	}
+
+	for _, n := range d.Notes {
+		if _, err := fmt.Fprintln(w, n.headline(p)); err != nil {
+			return err
+		}
+		if n.Pos == nil {
+			continue
+		}
+		if err := rn.excerpt(w, p, n.Pos.Raw(), n.End); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Write the source lines covering [start, end) to w, underlining the covered
+// text. If end is nil, only the byte at start is marked.
+func (rn *Renderer) excerpt(w io.Writer, p palette, start, end Position) error {
+	if start == nil || rn.Source == nil {
+		return nil
+	}
+
+	r := rn.Source(start.Filename())
+	if r == nil {
+		return nil
+	}
+
+	begin, ok := offsetOf(r, start)
+	if !ok {
+		return nil
+	}
+	limit := begin + 1
+	if end != nil {
+		if o, ok := offsetOf(r, end.Raw()); ok && o > begin {
+			limit = o
+		}
+	}
+
+	lines := []srcLine{}
+	for o := begin; ; {
+		ln, err := sourceLine(r, o)
+		if err != nil {
+			return nil
+		}
+		lines = append(lines, ln)
+
+		o = ln.next
+		if o >= limit {
+			break
+		}
+	}
+
+	width := len(fmt.Sprint(lines[len(lines)-1].num))
+	gutter := strings.Repeat(" ", width)
+
+	for ndx, ln := range lines {
+		from := 0
+		if ndx == 0 {
+			from = int(begin - ln.start)
+		}
+		to := len(ln.text)
+		if int(limit-ln.start) < to {
+			to = int(limit - ln.start)
+		}
+		if to <= from {
+			// Marking end of line or end of input
+			to = from + 1
+		}
+
+		num := p.paint(ansiBlue, fmt.Sprintf(" %*d |", width, ln.num))
+		bar := p.paint(ansiBlue, fmt.Sprintf(" %s |", gutter))
+		mark := underline(ln.text, from, to, ndx == 0)
+
+		if _, err := fmt.Fprintf(w, "%s %s\n%s %s\n",
+			num, ln.text, bar, p.paint(ansiGreen, mark)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// A line of source text, without its trailing newline.
+type srcLine struct {
+	num   int           // Line number (starting at 1)
+	start reader.Offset // Offset of the first byte of the line
+	next  reader.Offset // Offset of the first byte of the following line
+	text  []byte
+}
+
+// Return the source line containing offset o.
+func sourceLine(r reader.Reader, o reader.Offset) (srcLine, error) {
+	_, line, col := r.NameLineAndColumn(o, false)
+	if line == 0 {
+		return srcLine{}, io.EOF
+	}
+
+	start := o - reader.Offset(col-1)
+	end := start
+	for {
+		b, err := r.ByteAt(end)
+		if err != nil || b == '\n' {
+			break
+		}
+		end++
+	}
+
+	text, err := r.Content(start, end)
+	if err != nil {
+		return srcLine{}, err
+	}
+	text = []byte(strings.TrimSuffix(string(text), "\r"))
+
+	return srcLine{num: line, start: start, next: end + 1, text: text}, nil
+}
+
+// Return the offset of pos within r. Positions that do not record an offset
+// are located by line and column.
+func offsetOf(r reader.Reader, pos Position) (reader.Offset, bool) {
+	if o := pos.Offset(); o >= 0 {
+		return reader.Offset(o), true
+	}
+
+	if pos.Line() < 1 || pos.Column() < 1 {
+		return 0, false
+	}
+
+	line := 1
+	o := reader.Offset(0)
+	for line < pos.Line() {
+		b, err := r.ByteAt(o)
+		if err != nil {
+			return 0, false
+		}
+		if b == '\n' {
+			line++
+		}
+		o++
+	}
+
+	return o + reader.Offset(pos.Column()-1), true
+}
+
+// Return the offset of pos within r, as offsetOf does, and its line number,
+// or false if pos does not lie within the content of r.
+func lineOf(r reader.Reader, pos Position) (reader.Offset, int, bool) {
+	o, ok := offsetOf(r, pos)
+	if !ok {
+		return 0, 0, false
+	}
+	if o > 0 {
+		// Line information is recorded only as content is read.
+		if _, err := r.ByteAt(o - 1); err != nil {
+			return 0, 0, false
+		}
+	}
+	if l := r.Line(o, false); l > 0 {
+		return o, l, true
+	}
+	return 0, 0, false
+}
+
+// Return the marker line underlining bytes [from, to) of text. Leading
+// whitespace is copied from text so that tabs line up, and UTF-8 continuation
+// bytes do not advance the marker.
+func underline(text []byte, from, to int, caret bool) string {
+	var sb strings.Builder
+
+	for i := 0; i < from && i < len(text); i++ {
+		switch b := text[i]; {
+		case b == '\t':
+			sb.WriteByte('\t')
+		case b&0xC0 == 0x80:
+			// UTF-8 continuation byte
+		default:
+			sb.WriteByte(' ')
+		}
+	}
+	for i := len(text); i < from; i++ {
+		sb.WriteByte(' ')
+	}
+
+	for i := from; i < to; i++ {
+		if i < len(text) && text[i]&0xC0 == 0x80 {
+			continue
+		}
+		if caret && i == from {
+			sb.WriteByte('^')
+		} else {
+			sb.WriteByte('~')
+		}
+	}
+
+	return sb.String()
+}
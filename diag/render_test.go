@@ -0,0 +1,116 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bitc-lang/go-compileutil/position"
+	"github.com/bitc-lang/go-compileutil/reader"
+)
+
+const renderSource = "let y = 1;\nlet x = foo;\n\tbar(x);"
+
+const renderString = `<string>:2:9 (19): Error: undefined: foo
+ 2 | let x = foo;
+   |         ^
+<string>:3:2: Warning: unused result
+ 3 | 	bar(x);
+   | 	^
+`
+
+func TestRenderExcerpt(t *testing.T) {
+	r, err := reader.OnString(renderSource)
+	if err != nil {
+		t.Fatalf("Error %v instantiating Reader on string", err)
+	}
+
+	r.SetOffset(19)
+
+	diags := New()
+	diags.AddError(r.Position(), "undefined: foo")
+	diags.AddWarn(position.Pos("<string>", 3, 2), "unused result")
+
+	buf := bytes.NewBuffer([]byte{})
+	if err := NewRenderer(r).Render(buf, diags); err != nil {
+		t.Fatalf("Error %v rendering diagnostics", err)
+	}
+
+	if buf.String() != renderString {
+		t.Fatalf("Unexpected rendering:\n%s", buf.String())
+	}
+}
+
+func TestRenderNoSource(t *testing.T) {
+	diags := New()
+	diags.AddError(position.Pos("x", 2, 27), "Does not compute!")
+
+	buf := bytes.NewBuffer([]byte{})
+	rn := NewRenderer()
+	rn.Color = true
+	rn.Render(buf, diags)
+
+	expect := "\x1b[1mx:2:27\x1b[0m: \x1b[1;31mError\x1b[0m: \x1b[1mDoes not compute!\x1b[0m\n"
+	if buf.String() != expect {
+		t.Fatalf("Unexpected color rendering: %q", buf.String())
+	}
+}
+
+const spanString = `<string>:1:5 (4): Error: mismatched definitions
+ 1 | let y = 1;
+   |     ^~~~~~
+ 2 | let x = foo;
+   | ~~~
+`
+
+func TestRenderSpan(t *testing.T) {
+	r, err := reader.OnString(renderSource)
+	if err != nil {
+		t.Fatalf("Error %v instantiating Reader on string", err)
+	}
+
+	r.SetOffset(4)
+	start := r.Position().(*reader.Pos)
+	r.SetOffset(14)
+	end := r.Position().(*reader.Pos)
+
+	diags := New()
+	diags.AddSpan(start.To(*end), Error, "mismatched definitions")
+
+	buf := bytes.NewBuffer([]byte{})
+	NewRenderer(r).Render(buf, diags)
+
+	if buf.String() != spanString {
+		t.Fatalf("Unexpected span rendering:\n%s", buf.String())
+	}
+}
+
+func TestColorEnabled(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("CLICOLOR_FORCE", "0")
+	if ColorEnabled(buf) {
+		t.Fatalf("Color enabled for a buffer")
+	}
+
+	t.Setenv("CLICOLOR_FORCE", "1")
+	if !ColorEnabled(buf) {
+		t.Fatalf("CLICOLOR_FORCE did not enable color")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if ColorEnabled(buf) {
+		t.Fatalf("NO_COLOR did not disable color")
+	}
+
+	diags := New()
+	diags.AddError(position.Pos("x", 2, 27), "Does not compute!")
+	diags.Print(buf)
+	if buf.String() != "x:2:27: Error: Does not compute!\n" {
+		t.Fatalf("Unexpected plain output: %q", buf.String())
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/bitc-lang/go-compileutil/reader"
+)
+
+const sarifVersion = "2.1.0"
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// Options controlling SARIF output.
+type SARIFOptions struct {
+	ToolName       string // Name of the tool that produced the diagnostics.
+	ToolVersion    string // Version of the tool, if known.
+	InformationURI string // Where to find documentation for the tool.
+
+	// Return the rule identifier for a diagnostic, or the empty string if it
+	// has none. If RuleID is nil, the diagnostic code is used.
+	RuleID func(Diag) string
+	// Return the reader for the named input unit, or nil if the source text is
+	// not available. Source text is needed to report columns in Unicode code
+	// points; without it, byte columns are reported.
+	Source func(filename string) reader.Reader
+}
+
+// The subset of the SARIF 2.1.0 object model that we emit.
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool     `json:"tool"`
+	ColumnKind string        `json:"columnKind"`
+	Results    []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Rules          []sarifRule `json:"rules,omitempty"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID           string          `json:"ruleId,omitempty"`
+	RuleIndex        *int            `json:"ruleIndex,omitempty"`
+	Level            string          `json:"level"`
+	Message          sarifMessage    `json:"message"`
+	Locations        []sarifLocation `json:"locations,omitempty"`
+	RelatedLocations []sarifLocation `json:"relatedLocations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	Message          *sarifMessage         `json:"message,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int  `json:"startLine,omitempty"`
+	StartColumn int  `json:"startColumn,omitempty"`
+	EndLine     int  `json:"endLine,omitempty"`
+	EndColumn   int  `json:"endColumn,omitempty"`
+	ByteOffset  *int `json:"byteOffset,omitempty"`
+	ByteLength  *int `json:"byteLength,omitempty"`
+}
+
+// Return the SARIF result level corresponding to a diagnostic kind.
+func sarifLevel(k DiagKind) string {
+	switch {
+	case k.IsError():
+		return "error"
+	case k.Rank() < InfoRank:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// Return the column of pos in Unicode code points, if the source text of pos
+// is available, and otherwise its byte column.
+func (opts *SARIFOptions) column(pos Position) int {
+	if opts.Source == nil {
+		return pos.Column()
+	}
+	raw := pos.Raw()
+	r := opts.Source(raw.Filename())
+	if r == nil {
+		return pos.Column()
+	}
+	o, _, ok := lineOf(r, raw)
+	if !ok {
+		return pos.Column()
+	}
+	col := r.Column(o, false)
+	if col < 1 {
+		return pos.Column()
+	}
+	prefix, err := r.Content(o-reader.Offset(col-1), o)
+	if err != nil {
+		return pos.Column()
+	}
+	return 1 + utf8.RuneCount(prefix)
+}
+
+// Return the SARIF region describing the span [start, end), or nil if start
+// has no line or offset information. If end is nil, the region describes a
+// single position.
+func (opts *SARIFOptions) regionOf(start, end Position) *sarifRegion {
+	rgn := &sarifRegion{}
+	if start.Line() > 0 {
+		rgn.StartLine = start.Line()
+		rgn.StartColumn = opts.column(start)
+		if end != nil && end.Line() > 0 {
+			rgn.EndLine = end.Line()
+			rgn.EndColumn = opts.column(end)
+		}
+	}
+
+	if off := start.Offset(); off >= 0 {
+		rgn.ByteOffset = &off
+		if end != nil && end.Offset() >= off {
+			length := end.Offset() - off
+			rgn.ByteLength = &length
+		}
+	}
+
+	if rgn.StartLine == 0 && rgn.ByteOffset == nil {
+		return nil
+	}
+	return rgn
+}
+
+func (opts *SARIFOptions) locationOf(start, end Position) sarifLocation {
+	return sarifLocation{
+		PhysicalLocation: sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{URI: start.Filename()},
+			Region:           opts.regionOf(start, end),
+		},
+	}
+}
+
+// Write the diagnostics in the group to w as a SARIF 2.1.0 log containing a
+// single run, sorted by the active sorting algorithm.
+//
+// The log declares columns in Unicode code points. These are computed from the
+// source text supplied by opts.Source. Where source text is unavailable, the
+// byte columns of the diagnostic positions are reported instead, which agree
+// with code point columns only for ASCII input.
+func (c Diags) WriteSARIF(w io.Writer, opts SARIFOptions) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           opts.ToolName,
+			Version:        opts.ToolVersion,
+			InformationURI: opts.InformationURI,
+		}},
+		ColumnKind: "unicodeCodePoints",
+		Results:    []sarifResult{},
+	}
+
+	ruleIndex := map[string]int{}
+
+	for _, d := range c.sorted() {
+		res := sarifResult{
+			Level:   sarifLevel(d.Kind),
+			Message: sarifMessage{Text: d.Message},
+		}
+
+		id := d.Code
+		if opts.RuleID != nil {
+			id = opts.RuleID(d)
+		}
+		if id != "" {
+			ndx, ok := ruleIndex[id]
+			if !ok {
+				ndx = len(run.Tool.Driver.Rules)
+				ruleIndex[id] = ndx
+				run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: id})
+			}
+			res.RuleID = id
+			res.RuleIndex = &ndx
+		}
+
+		if d.Pos != nil {
+			res.Locations = []sarifLocation{opts.locationOf(d.Pos, d.End)}
+		}
+
+		// Notes with positions become related locations. Others are appended
+		// to the message text.
+		for _, n := range d.Notes {
+			if n.Pos == nil {
+				res.Message.Text += "\n" + n.String()
+				continue
+			}
+			loc := opts.locationOf(n.Pos, n.End)
+			loc.Message = &sarifMessage{Text: fmt.Sprintf("%s: %s", n.Label, n.Message)}
+			res.RelatedLocations = append(res.RelatedLocations, loc)
+		}
+
+		run.Results = append(run.Results, res)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(&sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []sarifRun{run},
+	})
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/bitc-lang/go-compileutil/position"
+	"github.com/bitc-lang/go-compileutil/reader"
+)
+
+func TestSARIF(t *testing.T) {
+	diags := New()
+	diags.AddWarn(position.OffsetPos("x.bit", 1, 2, 1), "Danger, Will Robinson!")
+	diags.AddSpan(position.SpanOf(position.OffsetPos("x.bit", 2, 3, 10),
+		position.OffsetPos("x.bit", 2, 6, 13)), Error, "Does not compute!")
+
+	buf := bytes.NewBuffer([]byte{})
+	err := diags.WriteSARIF(buf, SARIFOptions{
+		ToolName: "bitcc",
+		RuleID: func(d Diag) string {
+			if d.Kind == Error {
+				return "E0001"
+			}
+			return ""
+		},
+	})
+	if err != nil {
+		t.Fatalf("Error %v writing SARIF", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("Error %v reading back SARIF output", err)
+	}
+
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Results) != 2 {
+		t.Fatalf("Unexpected SARIF log structure:\n%s", buf.String())
+	}
+
+	run := log.Runs[0]
+	warn, e := run.Results[0], run.Results[1]
+	if warn.Level != "warning" || warn.RuleID != "" {
+		t.Fatalf("Unexpected SARIF warning result:\n%s", buf.String())
+	}
+	if e.Level != "error" || e.RuleID != "E0001" || *e.RuleIndex != 0 ||
+		len(run.Tool.Driver.Rules) != 1 {
+		t.Fatalf("Unexpected SARIF error result:\n%s", buf.String())
+	}
+
+	rgn := e.Locations[0].PhysicalLocation.Region
+	if rgn.StartLine != 2 || rgn.StartColumn != 3 || rgn.EndColumn != 6 ||
+		*rgn.ByteOffset != 10 || *rgn.ByteLength != 3 {
+		t.Fatalf("Unexpected SARIF region:\n%s", buf.String())
+	}
+}
+
+func TestSARIFCodePoints(t *testing.T) {
+	// 'é' is two bytes but one code point.
+	src := "let é = y\n"
+	r, _ := reader.OnString(src)
+	name := r.Filename(0, false)
+	yAt := strings.Index(src, "y")
+
+	diags := New()
+	diags.AddSpan(position.SpanOf(
+		position.OffsetPos(name, 1, yAt+1, yAt),
+		position.OffsetPos(name, 1, yAt+2, yAt+1)), Error, "undefined: y")
+
+	buf := bytes.NewBuffer([]byte{})
+	for _, source := range []func(string) reader.Reader{nil, NewRenderer(r).Source} {
+		buf.Reset()
+		if err := diags.WriteSARIF(buf, SARIFOptions{ToolName: "bitcc", Source: source}); err != nil {
+			t.Fatalf("Error %v writing SARIF", err)
+		}
+
+		var log sarifLog
+		if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+			t.Fatalf("Error %v reading back SARIF output", err)
+		}
+
+		// Without source text, byte columns are reported.
+		start, end := 10, 11
+		if source != nil {
+			start, end = 9, 10
+		}
+		rgn := log.Runs[0].Results[0].Locations[0].PhysicalLocation.Region
+		if log.Runs[0].ColumnKind != "unicodeCodePoints" ||
+			rgn.StartColumn != start || rgn.EndColumn != end || *rgn.ByteOffset != yAt {
+			t.Fatalf("Unexpected SARIF region:\n%s", buf.String())
+		}
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/bitc-lang/go-compileutil/reader"
+)
+
+// A Sink receives diagnostics as they are added to a group, allowing them to
+// be reported before the group is printed.
+//
+// A group forwards each diagnostic once it can no longer change: when the next
+// diagnostic is added, when the group is flushed, or immediately if the
+// diagnostic is fatal. Diagnostics added while a mark is unresolved (see
+// Diags.Mark) are held until the outermost mark is committed, and are never
+// forwarded if they are rolled back. The one exception is a fatal diagnostic,
+// which is forwarded at once even under a mark, though the diagnostics held
+// before it are not.
+//
+// Because the most recent diagnostic is held back, a program that stops
+// without adding a fatal diagnostic loses it unless the group is flushed.
+// Programs should arrange for the group to be flushed however they exit:
+//
+//	diags.Sinks = []diag.Sink{diag.NewTextSink(os.Stderr)}
+//	defer diags.Flush()
+//
+// Sinks shared by the shards of a Collector must be safe for concurrent use.
+// The sinks in this package are.
+type Sink interface {
+	Emit(d Diag)
+}
+
+// Forward recorded diagnostics to the group's sinks. If all is false, the most
+// recently recorded diagnostic is held back, since notes may still be attached
+// to it.
+func (c Diags) emit(all bool) {
+	if c.marks > 0 {
+		return
+	}
+
+	upTo := len(c.diags)
+	if !all && upTo > 0 {
+		upTo--
+	}
+	c.send(upTo)
+}
+
+// Forward the recorded diagnostics preceding index upTo that have not yet been
+// sent to the group's sinks.
+func (c Diags) send(upTo int) {
+	for ; c.unsent < upTo; c.unsent++ {
+		if d := c.diags[c.unsent]; !d.forwarded {
+			for _, s := range c.Sinks {
+				s.Emit(d)
+			}
+		}
+	}
+}
+
+// Forward the recorded fatal diagnostic d, and any diagnostics held before it,
+// to the group's sinks. Under a mark, only d itself is forwarded.
+func (c Diags) emitFatal(d Diag) {
+	if c.marks == 0 {
+		c.send(len(c.diags))
+		return
+	}
+
+	d.forwarded = true
+	for _, s := range c.Sinks {
+		s.Emit(d)
+	}
+}
+
+// Forward all diagnostics that have not yet been sent to the group's sinks,
+// including the most recently added one. Diagnostics added while a mark is
+// unresolved are not forwarded until the outermost mark is committed.
+func (c Diags) Flush() Diags {
+	c.emit(true)
+	return c
+}
+
+// A Sink that writes each diagnostic to an io.Writer, either as rendered text
+// or as JSON lines. Write errors stop further output, and are reported by Err.
+type WriterSink struct {
+	mu  sync.Mutex
+	w   io.Writer
+	rn  *Renderer     // Renderer for text output
+	enc *json.Encoder // Encoder for JSON lines output
+	err error
+}
+
+// Return a sink that renders diagnostics to w as text, with source excerpts
+// drawn from the provided readers. Output is colored if ColorEnabled(w) is
+// true.
+func NewTextSink(w io.Writer, readers ...reader.Reader) *WriterSink {
+	rn := NewRenderer(readers...)
+	rn.Color = ColorEnabled(w)
+	return &WriterSink{w: w, rn: rn}
+}
+
+// Return a sink that writes diagnostics to w as JSON lines, in the form
+// written by Diags.WriteJSONLines.
+func NewJSONLinesSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w, enc: json.NewEncoder(w)}
+}
+
+// Implement the Sink interface.
+func (s *WriterSink) Emit(d Diag) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return
+	}
+	if s.enc != nil {
+		s.err = s.enc.Encode(d)
+	} else {
+		s.err = s.rn.RenderDiag(s.w, d)
+	}
+}
+
+// Return the first error encountered writing diagnostics, if any.
+func (s *WriterSink) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+// Render the messages of a text sink in the given locale, using translations
+// from cat (see Localize). Has no effect on a JSON lines sink, which always
+// writes messages in English.
+func (s *WriterSink) Localized(cat Catalog, locale string) *WriterSink {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.rn != nil {
+		s.rn.Catalog, s.rn.Locale = cat, locale
+	}
+	return s
+}
+
+// A Sink that records the diagnostics it receives, for use in tests.
+type Recorder struct {
+	mu    sync.Mutex
+	diags []Diag
+}
+
+// Implement the Sink interface.
+func (r *Recorder) Emit(d Diag) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.diags = append(r.diags, d)
+}
+
+// Return the diagnostics received so far, in the order received.
+func (r *Recorder) Diags() []Diag {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Diag{}, r.diags...)
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bitc-lang/go-compileutil/position"
+)
+
+func TestSink(t *testing.T) {
+	rec := &Recorder{}
+	var buf bytes.Buffer
+	text := NewTextSink(&buf)
+
+	diags := New()
+	diags.Sinks = []Sink{rec, text}
+
+	diags.AddError(position.Pos("x", 3, 1), "Second")
+	if len(rec.Diags()) != 0 {
+		t.Fatalf("Most recent diagnostic was sent before it was complete")
+	}
+	diags.AddNote(nil, "attached later")
+
+	diags.AddWarn(position.Pos("x", 1, 1), "First")
+	if got := rec.Diags(); len(got) != 1 || len(got[0].Notes) != 1 {
+		t.Fatalf("Diagnostic was not sent with its notes: %v", got)
+	}
+
+	m := diags.Mark()
+	diags.AddError(position.Pos("x", 2, 1), "Speculative")
+	diags.AddError(position.Pos("x", 2, 2), "Also speculative")
+	diags.Flush()
+	diags.Rollback(m)
+	diags.Flush()
+
+	got := rec.Diags()
+	if len(got) != 2 || got[1].Message != "First" {
+		t.Fatalf("Unexpected diagnostics sent: %v", got)
+	}
+
+	m = diags.Mark()
+	diags.AddError(position.Pos("x", 4, 1), "Kept")
+	diags.Commit(m)
+	diags.Flush()
+	if got := rec.Diags(); len(got) != 3 || got[2].Message != "Kept" {
+		t.Fatalf("Committed diagnostic was not sent: %v", got)
+	}
+
+	// Sinks see arrival order; the group still prints sorted.
+	if !strings.HasPrefix(diags.String(), "x:1:1: Warning: First") {
+		t.Fatalf("Buffered output is not sorted:\n%s", diags)
+	}
+	if text.Err() != nil || !strings.HasPrefix(buf.String(), "x:3:1: Error: Second") {
+		t.Fatalf("Unexpected text sink output:\n%s", buf.String())
+	}
+}
+
+func TestSinkFatal(t *testing.T) {
+	rec := &Recorder{}
+	var buf bytes.Buffer
+
+	diags := New()
+	diags.OnFatal = func(Diags, Diag) {}
+	diags.Sinks = []Sink{rec, NewJSONLinesSink(&buf)}
+
+	// Outside a mark, the fatal diagnostic is sent with those before it.
+	diags.AddError(position.Pos("x", 1, 1), "Before")
+	diags.AddFatal(position.Pos("x", 2, 1), "Boom")
+	if got := rec.Diags(); len(got) != 2 || got[1].Kind != Fatal {
+		t.Fatalf("Fatal diagnostic was not sent immediately: %v", got)
+	}
+
+	// Under a mark, only the fatal diagnostic itself is sent.
+	m := diags.Mark()
+	diags.AddError(position.Pos("x", 3, 1), "Speculative")
+	diags.AddFatal(position.Pos("x", 4, 1), "Speculative boom")
+	diags.Rollback(m)
+	diags.Flush()
+	if got := rec.Diags(); len(got) != 3 || got[2].Message != "Speculative boom" {
+		t.Fatalf("Speculative diagnostics were sent: %v", got)
+	}
+
+	// A committed fatal diagnostic is not sent twice.
+	m = diags.Mark()
+	diags.AddError(position.Pos("x", 5, 1), "Kept")
+	diags.AddFatal(position.Pos("x", 6, 1), "Kept boom")
+	diags.Commit(m)
+	diags.Flush()
+	got := rec.Diags()
+	if len(got) != 5 || got[3].Message != "Kept boom" || got[4].Message != "Kept" {
+		t.Fatalf("Unexpected diagnostics sent: %v", got)
+	}
+
+	back, err := ReadJSONLines(&buf)
+	if err != nil || back.Len() != 5 {
+		t.Fatalf("JSON lines sink output did not round trip: %v", err)
+	}
+}
+
+func TestSinkMerge(t *testing.T) {
+	rec := &Recorder{}
+
+	a := New()
+	a.Sinks = []Sink{rec}
+	a.AddError(position.Pos("x", 1, 1), "From a")
+	b := New()
+	b.AddError(position.Pos("x", 2, 1), "From b")
+
+	a.With(b).Flush()
+	if got := rec.Diags(); len(got) != 2 {
+		t.Fatalf("Merged group did not send held diagnostics: %v", got)
+	}
+
+	// Diagnostics already sent by a group are not sent again.
+	a.AddError(position.Pos("x", 3, 1), "Also from a")
+	a.Flush()
+	a.With(New()).Flush()
+	if got := rec.Diags(); len(got) != 3 {
+		t.Fatalf("Merged group sent diagnostics twice: %v", got)
+	}
+
+	rec = &Recorder{}
+	base := New()
+	base.Sinks = []Sink{rec}
+	coll := NewCollector(base)
+	coll.Shard("fn1").AddError(position.Pos("x", 1, 1), "In fn1")
+	coll.Shard("fn2").AddError(position.Pos("x", 2, 1), "In fn2")
+
+	coll.Diags().Flush()
+	if got := rec.Diags(); len(got) != 2 {
+		t.Fatalf("Collector did not send held diagnostics: %v", got)
+	}
+}
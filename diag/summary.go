@@ -0,0 +1,64 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Return the number of diagnostics of the given kind recorded in the group.
+//
+// Diagnostics dropped by suppression, deduplication, or the error limit are
+// not counted, nor is the diagnostic reporting that the error limit was
+// reached. Diagnostics hidden by the Cascade mode are counted.
+func (c Diags) Count(kind DiagKind) int {
+	return c.counts[kind]
+}
+
+// Return the number of errors, including fatal errors and errors of
+// registered kinds, recorded in the group.
+func (c Diags) errorCount() int {
+	n := 0
+	for kind, count := range c.counts {
+		if kind.IsError() {
+			n += count
+		}
+	}
+	return n
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// Return a conventional summary line for the group, such as
+//
+//	3 errors, 12 warnings generated
+//
+// Fatal diagnostics, and those of registered error kinds, are counted as
+// errors. The number of suppressed diagnostics, if any, is given in
+// parentheses.
+func (c Diags) Summary() string {
+	parts := []string{}
+	if n := c.errorCount(); n > 0 {
+		parts = append(parts, plural(n, "error"))
+	}
+	if n := c.Count(Warning); n > 0 {
+		parts = append(parts, plural(n, "warning"))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "no errors")
+	}
+
+	s := strings.Join(parts, ", ") + " generated"
+	if c.suppressed > 0 {
+		s = fmt.Sprintf("%s (%d suppressed)", s, c.suppressed)
+	}
+	return s
+}
@@ -0,0 +1,234 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bitc-lang/go-compileutil/position"
+	"github.com/bitc-lang/go-compileutil/reader"
+)
+
+// A region of input within which diagnostics are suppressed, typically
+// established by a comment such as
+//
+//	x := 5 // nolint:W0042
+type suppression struct {
+	pragma   Position // Position of the suppressing pragma.
+	filename string
+	begin    int          // Offset of the first suppressed byte.
+	end      int          // Offset just past the last suppressed byte.
+	codes    []string     // Codes or categories suppressed, or empty for all.
+	used     atomic.Int64 // Number of diagnostics suppressed.
+}
+
+// Return true iff s suppresses d.
+func (s *suppression) covers(d Diag) bool {
+	if d.Pos == nil || d.Kind == Fatal {
+		return false
+	}
+
+	raw := d.Raw()
+	off := raw.Offset()
+	if off < s.begin || off >= s.end || raw.Filename() != s.filename {
+		return false
+	}
+
+	if len(s.codes) == 0 {
+		return true
+	}
+	for _, code := range s.codes {
+		if code == d.Code || (d.Category != "" && code == d.Category) {
+			return true
+		}
+	}
+	return false
+}
+
+// Suppress diagnostics whose position lies within region and that have one of
+// the given codes or categories, or all diagnostics if no codes are given.
+// The pragma position is the location of the construct establishing the
+// suppression, and is used to report unused suppressions.
+//
+// Suppression regions are matched by offset, so diagnostics whose positions
+// do not record an offset are never suppressed. Fatal diagnostics are never
+// suppressed. Suppressed diagnostics are included in the count returned by
+// Suppressed.
+func (c Diags) AddSuppression(pragma Position, region Span, codes ...string) Diags {
+	start := region.Start().Raw()
+	c.suppressions = append(c.suppressions, &suppression{
+		pragma:   pragma,
+		filename: start.Filename(),
+		begin:    start.Offset(),
+		end:      region.End().Raw().Offset(),
+		codes:    codes,
+	})
+	return c
+}
+
+// Add suppression s to the group unless it is already present. Groups derived
+// from a common ancestor share that ancestor's suppressions.
+func (c Diags) addSuppression(s *suppression) {
+	for _, have := range c.suppressions {
+		if have == s {
+			return
+		}
+	}
+	c.suppressions = append(c.suppressions, s)
+}
+
+// Return true iff d is covered by one of the group's suppressions, recording
+// the use of that suppression.
+func (c Diags) isSuppressed(d Diag) bool {
+	for _, s := range c.suppressions {
+		if s.covers(d) {
+			s.used.Add(1)
+			return true
+		}
+	}
+	return false
+}
+
+// Scan the content of r for suppression pragmas introduced by marker, adding a
+// suppression for each.
+//
+// The marker is recognized only where it stands alone: it must not follow an
+// identifier character, and must be followed by the end of the line,
+// whitespace, or ':'. The marker may be followed by an optional ':' and a comma-separated list of
+// codes or categories, so that with the marker "nolint"
+//
+//	x := 5 // nolint:W0042,unused
+//
+// suppresses diagnostics with code W0042 or category "unused" on that line,
+// and with the marker "#pragma diag ignore"
+//
+//	#pragma diag ignore W0042
+//
+// suppresses W0042. A pragma without codes suppresses all diagnostics. A pragma
+// that is the only thing on its line, other than leading whitespace and
+// comment punctuation, applies to the following line rather than its own.
+func (c Diags) ScanSuppressions(r reader.Reader, marker string) Diags {
+	content, err := readAll(r)
+	if err != nil {
+		return c
+	}
+	name := r.Filename(0, false)
+
+	lineStart := 0
+	for lineStart < len(content) {
+		lineEnd := len(content)
+		if nl := bytes.IndexByte(content[lineStart:], '\n'); nl >= 0 {
+			lineEnd = lineStart + nl
+		}
+		line := content[lineStart:lineEnd]
+
+		if at := markerIndex(line, marker); at >= 0 {
+			codes := pragmaCodes(string(line[at+len(marker):]))
+
+			begin, end := lineStart, lineEnd+1
+			if isPragmaOnly(line[:at]) {
+				begin, end = end, len(content)+1
+				if begin < len(content) {
+					if nl := bytes.IndexByte(content[begin:], '\n'); nl >= 0 {
+						end = begin + nl + 1
+					}
+				}
+			}
+
+			off := lineStart + at
+			_, l, col := r.NameLineAndColumn(reader.Offset(off), false)
+			pragma := position.OffsetPos(name, l, col, off)
+			c.AddSuppression(pragma, position.SpanOf(
+				position.OffsetPos(name, 0, 0, begin),
+				position.OffsetPos(name, 0, 0, end)), codes...)
+		}
+
+		lineStart = lineEnd + 1
+	}
+
+	return c
+}
+
+// Return the index of the first occurrence of marker in line that stands alone:
+// one that is not preceded by an identifier character, and that is followed by
+// the end of the line, whitespace, or ':'. Returns -1 if there is none.
+func markerIndex(line []byte, marker string) int {
+	for from := 0; from < len(line); {
+		at := bytes.Index(line[from:], []byte(marker))
+		if at < 0 {
+			return -1
+		}
+		at += from
+
+		before, after := rune(' '), rune(' ')
+		if at > 0 {
+			before, _ = utf8.DecodeLastRune(line[:at])
+		}
+		if next := at + len(marker); next < len(line) {
+			after, _ = utf8.DecodeRune(line[next:])
+		}
+		if !isIdentRune(before) && (unicode.IsSpace(after) || after == ':') {
+			return at
+		}
+		from = at + 1
+	}
+	return -1
+}
+
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// Return the codes listed after a pragma marker.
+func pragmaCodes(rest string) []string {
+	rest = strings.TrimPrefix(rest, ":")
+	rest = strings.TrimLeft(rest, " \t")
+	if i := strings.IndexFunc(rest, unicode.IsSpace); i >= 0 {
+		rest = rest[:i]
+	}
+	if rest == "" {
+		return nil
+	}
+	return strings.Split(rest, ",")
+}
+
+// Return true iff the text preceding a pragma marker consists only of
+// whitespace and comment punctuation.
+func isPragmaOnly(prefix []byte) bool {
+	for _, r := range string(prefix) {
+		if isIdentRune(r) {
+			return false
+		}
+		if !unicode.IsSpace(r) && !unicode.IsPunct(r) && !unicode.IsSymbol(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Add a warning at the position of each suppression that has not suppressed
+// any diagnostic. These warnings are not themselves subject to suppression.
+func (c Diags) ReportUnusedSuppressions() Diags {
+	suppressions := c.suppressions
+	c.suppressions = nil
+	defer func() { c.suppressions = suppressions }()
+
+	for _, s := range suppressions {
+		if s.used.Load() > 0 {
+			continue
+		}
+		msg := "unused suppression"
+		if len(s.codes) > 0 {
+			msg = fmt.Sprintf("unused suppression of %s", strings.Join(s.codes, ", "))
+		}
+		c.Add(s.pragma, Warning, msg)
+	}
+	return c
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diag
+
+import (
+	"testing"
+
+	"github.com/bitc-lang/go-compileutil/position"
+	"github.com/bitc-lang/go-compileutil/reader"
+)
+
+const suppressSource = `let x = 5 // nolint:W0042
+// nolint
+let y = bad
+let z = 1 // nolint:E0001
+let w = 2`
+
+const suppressString = `<string>:4:14 (61): Warning: unused suppression of E0001
+<string>:5:5: Warning[W0042]: unused variable
+`
+
+func TestSuppressions(t *testing.T) {
+	r, err := reader.OnString(suppressSource)
+	if err != nil {
+		t.Fatalf("Error %v instantiating Reader on string", err)
+	}
+
+	codes := NewRegistry()
+	unused := codes.MustRegister(Descriptor{Code: "W0042", Kind: Warning})
+
+	diags := New()
+	diags.ScanSuppressions(r, "nolint")
+
+	diags.AddCoded(position.OffsetPos("<string>", 1, 5, 4), unused, "unused variable")
+	diags.AddError(position.OffsetPos("<string>", 3, 9, 44), "undefined: bad")
+	// Not suppressed, because the position has no offset:
+	diags.AddCoded(position.Pos("<string>", 5, 5), unused, "unused variable")
+	diags.ReportUnusedSuppressions()
+
+	if diags.Suppressed() != 2 || diags.HasError {
+		t.Fatalf("Expected two suppressed diagnostics, got %d", diags.Suppressed())
+	}
+
+	if diags.String() != suppressString {
+		t.Fatalf("Unexpected diagnostics with suppressions:\n%s", diags)
+	}
+}
+
+func TestSuppressionMarkerBoundaries(t *testing.T) {
+	r, err := reader.OnString("let nolinter = 1\n" +
+		"x := \"// nolint\"\n" +
+		"y := 2 //nolint\n")
+	if err != nil {
+		t.Fatalf("Error %v instantiating Reader on string", err)
+	}
+
+	diags := New().ScanSuppressions(r, "nolint")
+	if len(diags.suppressions) != 1 {
+		t.Fatalf("Expected one suppression, found %d", len(diags.suppressions))
+	}
+
+	diags.ReportUnusedSuppressions()
+	if diags.String() != "<string>:3:10 (43): Warning: unused suppression\n" {
+		t.Fatalf("Unexpected unused suppressions:\n%s", diags)
+	}
+}
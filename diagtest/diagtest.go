@@ -0,0 +1,250 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+// Package diagtest checks the diagnostics produced for test inputs against
+// expectations written in the inputs themselves, in the manner of the Go
+// project's errorcheck tests.
+//
+// An expectation is a severity keyword (FATAL, ERROR, WARNING, INFO, NOTE,
+// HELP, HINT, or the upper-cased name of a registered kind) followed by one or
+// more quoted regular expressions, usually written in a comment:
+//
+//	y := x + 1 // ERROR "undefined: x"
+//	var z int  // WARNING "z declared and not used" INFO `declared here`
+//
+// Each regular expression calls for one diagnostic of that severity on that
+// line whose message it matches. Expectations are recognized wherever they
+// appear on a line, so the harness does not depend on the comment syntax of
+// the language under test.
+//
+// A typical test reads:
+//
+//	func TestTypeCheck(t *testing.T) {
+//		diagtest.Check(t, "testdata/undefined.src", func(r reader.Reader) diag.Diags {
+//			diags := diag.New()
+//			typeCheck(parse(r, diags), diags)
+//			return diags
+//		})
+//	}
+package diagtest
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bitc-lang/go-compileutil/diag"
+	"github.com/bitc-lang/go-compileutil/reader"
+)
+
+// A diagnostic called for by an annotation in a test input.
+type Expectation struct {
+	Filename string
+	Line     int
+	Kind     diag.DiagKind
+	Pattern  *regexp.Regexp // Pattern that the diagnostic's message must match.
+}
+
+func (e Expectation) String() string {
+	return fmt.Sprintf("%s:%d: %v %q", e.Filename, e.Line, e.Kind, e.Pattern)
+}
+
+// Matches a severity keyword followed by its quoted patterns.
+var annotationRE = regexp.MustCompile(
+	"(?:^|\\s)([A-Z]+)((?:\\s+(?:\"(?:[^\"\\\\]|\\\\.)*\"|`[^`]*`))+)")
+
+// Matches a single quoted pattern.
+var patternRE = regexp.MustCompile("\"(?:[^\"\\\\]|\\\\.)*\"|`[^`]*`")
+
+// Return the diagnostic kind named by an annotation keyword.
+func kindOf(keyword string) (diag.DiagKind, bool) {
+	for _, k := range diag.Kinds() {
+		if strings.ToUpper(k.String()) == keyword {
+			return k, true
+		}
+	}
+	return 0, false
+}
+
+// Return the expectations written in the content of r, in order of
+// appearance. It is an error for an expectation to contain an invalid regular
+// expression.
+//
+// Expectations does not move the reader.
+func Expectations(r reader.Reader) ([]Expectation, error) {
+	name := r.Filename(0, false)
+
+	end := reader.Offset(0)
+	for {
+		if _, err := r.ByteAt(end); err != nil {
+			break
+		}
+		end++
+	}
+	content, err := r.Content(0, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var expect []Expectation
+	for ndx, line := range strings.Split(string(content), "\n") {
+		for _, m := range annotationRE.FindAllStringSubmatch(line, -1) {
+			kind, ok := kindOf(m[1])
+			if !ok {
+				continue
+			}
+			for _, quoted := range patternRE.FindAllString(m[2], -1) {
+				s, err := strconv.Unquote(quoted)
+				var re *regexp.Regexp
+				if err == nil {
+					re, err = regexp.Compile(s)
+				}
+				if err != nil {
+					return nil, fmt.Errorf("%s:%d: bad expectation %s: %v", name, ndx+1, quoted, err)
+				}
+				expect = append(expect, Expectation{
+					Filename: name,
+					Line:     ndx + 1,
+					Kind:     kind,
+					Pattern:  re,
+				})
+			}
+		}
+	}
+
+	return expect, nil
+}
+
+// A discrepancy between expected and reported diagnostics.
+type problem struct {
+	filename string // File name, or the empty string if there is no position.
+	line     int
+	text     string
+}
+
+// Compare the diagnostics reported by diags against the expectations written
+// in the content of r, returning one line for each expectation that was not
+// met and for each diagnostic that was not expected. The result is empty if
+// the diagnostics match exactly.
+//
+// A diagnostic meets an expectation if it has the expected kind, its position
+// lies on the expected line of r, and its message matches the expected
+// pattern. Each diagnostic meets at most one expectation. Notes are not
+// checked. Diagnostics are examined as reported, so diagnostics hidden by the
+// group's Cascade setting cannot meet expectations.
+func Verify(r reader.Reader, diags diag.Diags) ([]string, error) {
+	expect, err := Expectations(r)
+	if err != nil {
+		return nil, err
+	}
+	return verify(expect, diags), nil
+}
+
+// Compare the diagnostics reported by diags against expect, as Verify does.
+// A nil group reports no diagnostics.
+func verify(expect []Expectation, diags diag.Diags) []string {
+	var reported []diag.Diag
+	if diags != nil {
+		reported = diags.All()
+	}
+
+	met := make([]bool, len(expect))
+	var problems []problem
+
+	for _, d := range reported {
+		if meet(expect, met, d) {
+			continue
+		}
+		p := problem{text: fmt.Sprintf("<no position>: unexpected %v: %s", d.Kind, d.Message)}
+		if raw := d.Raw(); raw != nil {
+			p.filename, p.line = raw.Filename(), raw.Line()
+			p.text = fmt.Sprintf("%s:%d: unexpected %v: %s", p.filename, p.line, d.Kind, d.Message)
+		}
+		problems = append(problems, p)
+	}
+
+	for ndx, e := range expect {
+		if !met[ndx] {
+			problems = append(problems, problem{e.Filename, e.Line,
+				fmt.Sprintf("%s:%d: missing %v matching %q", e.Filename, e.Line, e.Kind, e.Pattern)})
+		}
+	}
+
+	// Report by file and line, with position-less diagnostics last.
+	sort.SliceStable(problems, func(i, j int) bool {
+		pi, pj := problems[i], problems[j]
+		if (pi.filename == "") != (pj.filename == "") {
+			return pj.filename == ""
+		}
+		if pi.filename != pj.filename {
+			return pi.filename < pj.filename
+		}
+		return pi.line < pj.line
+	})
+
+	text := make([]string, len(problems))
+	for ndx, p := range problems {
+		text[ndx] = p.text
+	}
+	return text
+}
+
+// Mark the first unmet expectation that d meets, returning true iff there is
+// one.
+func meet(expect []Expectation, met []bool, d diag.Diag) bool {
+	raw := d.Raw()
+	if raw == nil {
+		return false
+	}
+	for ndx, e := range expect {
+		if !met[ndx] && e.Kind == d.Kind && e.Line == raw.Line() &&
+			e.Filename == raw.Filename() && e.Pattern.MatchString(d.Message) {
+			met[ndx] = true
+			return true
+		}
+	}
+	return false
+}
+
+// Run a test on the named input file: run is called with a reader on the
+// file, and the diagnostics it returns are compared against the expectations
+// written in the file (see Verify). Each discrepancy is reported as a test
+// error.
+//
+// If run adds a fatal diagnostic and the group's OnFatal policy panics, the
+// fatal diagnostic is recovered and the diagnostics of the group are checked.
+func Check(t testing.TB, filename string, run func(r reader.Reader) diag.Diags) {
+	t.Helper()
+
+	r, err := reader.OnFile(filename)
+	if err != nil {
+		t.Fatalf("Unable to open %s (error %v)", filename, err)
+	}
+	defer r.Close()
+
+	CheckReader(t, r, run)
+}
+
+// Run a test as Check does, on input supplied by r.
+func CheckReader(t testing.TB, r reader.Reader, run func(r reader.Reader) diag.Diags) {
+	t.Helper()
+
+	// Read the expectations first, since run may consume or close the reader.
+	expect, err := Expectations(r)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	var diags diag.Diags
+	if fe := diag.CatchFatal(func() { diags = run(r) }); fe != nil {
+		diags = fe.Diags
+	}
+
+	for _, p := range verify(expect, diags) {
+		t.Errorf("%s", p)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2022 Jonathan S. Shapiro. All rights reserved.
+// Use of this source code is governed by terms that can be
+// found in the LICENSE file.
+
+package diagtest
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/bitc-lang/go-compileutil/diag"
+	"github.com/bitc-lang/go-compileutil/position"
+	"github.com/bitc-lang/go-compileutil/reader"
+	"github.com/bitc-lang/go-compileutil/testing_cwd"
+)
+
+type Dummy = testing_cwd.Dummy
+
+var badRE = regexp.MustCompile(`\bbad_\w+`)
+
+// A toy checker reporting each identifier starting with "bad_" as undefined,
+// and each comment starting with TODO as a warning. Comments start with '#'
+// or "//".
+func check(r reader.Reader) diag.Diags {
+	diags := diag.New()
+	name := r.Filename(0, false)
+
+	content := []byte{}
+	for o := reader.Offset(0); ; o++ {
+		b, err := r.ByteAt(o)
+		if err != nil {
+			break
+		}
+		content = append(content, b)
+	}
+
+	off := 0
+	for ndx, line := range strings.Split(string(content), "\n") {
+		code := line
+		if at := strings.IndexAny(line, "#/"); at >= 0 {
+			code = line[:at]
+			comment := strings.TrimLeft(line[at:], "#/ ")
+			if strings.HasPrefix(comment, "TODO") {
+				col := len(line) - len(comment)
+				diags.AddWarn(position.OffsetPos(name, ndx+1, col+1, off+col),
+					"TODO left for later")
+			}
+		}
+
+		for _, m := range badRE.FindAllStringIndex(code, -1) {
+			diags.AddError(position.OffsetPos(name, ndx+1, m[0]+1, off+m[0]),
+				"undefined: "+code[m[0]:m[1]])
+		}
+		off += len(line) + 1
+	}
+	return diags
+}
+
+func TestCheck(t *testing.T) {
+	Check(t, "diagtest/diagtest_test1", check)
+}
+
+func TestExpectations(t *testing.T) {
+	r, _ := reader.OnFile("diagtest/diagtest_test1")
+	expect, err := Expectations(r)
+	if err != nil {
+		t.Fatalf("Unable to read expectations (error %v)", err)
+	}
+
+	got := []string{}
+	for _, e := range expect {
+		got = append(got, e.String())
+	}
+	want := []string{
+		`diagtest/diagtest_test1:6: Error "undefined: bad_a"`,
+		`diagtest/diagtest_test1:7: Error "undefined: bad_b"`,
+		`diagtest/diagtest_test1:7: Error "undefined: bad_c"`,
+		`diagtest/diagtest_test1:9: Warning "TODO"`,
+		`diagtest/diagtest_test1:10: Error "bad_d"`,
+		`diagtest/diagtest_test1:10: Warning "left for later"`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Unexpected expectations:\n%s", strings.Join(got, "\n"))
+	}
+	if r.Offset() != 0 {
+		t.Fatalf("Expectations moved the reader")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	r, _ := reader.OnString(
+		"let a = bad_a  // ERROR \"undefined: bad_a\"\n" +
+			"let b = 1      // ERROR \"undefined: b\"\n" +
+			"let c = bad_c\n")
+
+	diags := check(r)
+	diags.AddInfo(nil, "checked 3 lines")
+
+	problems, err := Verify(r, diags)
+	if err != nil {
+		t.Fatalf("Verify failed (error %v)", err)
+	}
+	want := []string{
+		`<string>:2: missing Error matching "undefined: b"`,
+		`<string>:3: unexpected Error: undefined: bad_c`,
+		`<no position>: unexpected Info: checked 3 lines`,
+	}
+	if !reflect.DeepEqual(problems, want) {
+		t.Fatalf("Unexpected problems:\n%s", strings.Join(problems, "\n"))
+	}
+
+	r, _ = reader.OnString("x // ERROR \"(\"\n")
+	if _, err := Verify(r, diag.New()); err == nil {
+		t.Fatalf("Invalid pattern was accepted")
+	}
+}
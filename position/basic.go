@@ -61,3 +61,58 @@ func Pos(nm string, line, col int) *BasicPos {
 func OffsetPos(nm string, line, col int, off int) *BasicPos {
 	return &BasicPos{filename: nm, line: line, column: col, offset: off}
 }
+
+// A basic span value.
+//
+// Like BasicPos, this type exists primarily for testing. The start and end
+// positions can be any Position implementation.
+type BasicSpan struct {
+	start Position
+	end   Position
+}
+
+// -------------------------------------------------------------------------
+// Implement Stringer interface
+func (sp *BasicSpan) String() string {
+	return SpanString(sp.start, sp.end)
+}
+
+// -------------------------------------------------------------------------
+// Implement Span interface
+func (sp *BasicSpan) Start() Position {
+	return sp.start
+}
+func (sp *BasicSpan) End() Position {
+	return sp.end
+}
+
+// Return a span covering [start, end). The two positions must name the same
+// input unit.
+func SpanOf(start, end Position) *BasicSpan {
+	if start.Filename() != end.Filename() {
+		panic(fmt.Sprintf("Span %s to %s crosses input units", start, end))
+	}
+	return &BasicSpan{start: start, end: end}
+}
+
+// Return a human-readable representation of the span [start, end), in the
+// form
+//
+//	filename:line:column-line:column
+//
+// or, when the span lies within a single line,
+//
+//	filename:line:column-column
+func SpanString(start, end Position) string {
+	if start.Line() <= 0 || end.Line() <= 0 {
+		return start.Filename()
+	}
+
+	if start.Line() == end.Line() {
+		return fmt.Sprintf("%s:%d:%d-%d",
+			start.Filename(), start.Line(), start.Column(), end.Column())
+	}
+
+	return fmt.Sprintf("%s:%d:%d-%d:%d",
+		start.Filename(), start.Line(), start.Column(), end.Line(), end.Column())
+}
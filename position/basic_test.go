@@ -22,3 +22,12 @@ func TestCWD(t *testing.T) {
 		t.Fatalf("Bad string form of invalid pos: %s", p)
 	}
 }
+
+func TestSpan(t *testing.T) {
+	if sp := SpanOf(Pos("file", 1, 2), Pos("file", 1, 7)); sp.String() != "file:1:2-7" {
+		t.Fatalf("Bad string form of single-line span: %s", sp)
+	}
+	if sp := SpanOf(Pos("file", 1, 2), Pos("file", 3, 4)); sp.String() != "file:1:2-3:4" {
+		t.Fatalf("Bad string form of multi-line span: %s", sp)
+	}
+}
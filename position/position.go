@@ -5,6 +5,9 @@
 
 // Abstraction of a position for use by consumers, with a basic implementation.
 //
+// A Span describes a range of input by its start and end positions. Both
+// positions must refer to the same input unit.
+//
 // This interface intentionally makes no provision for updating the position.
 // If it did, compiler implementations would incur two forms of overhead:
 //
@@ -32,3 +35,12 @@ type Position interface {
 	Offset() int      // Return the byte offset (starting at 0) of this position.
 	Raw() Position    // Return aform of the position that does not take line number directives into account.
 }
+
+// The span abstraction: a half-open range [Start, End) of input within a
+// single input unit.
+type Span interface {
+	fmt.Stringer
+
+	Start() Position // Return the position of the first byte of the span.
+	End() Position   // Return the position just past the last byte of the span.
+}
@@ -108,7 +108,7 @@ func (p RawPos) Filename() string {
 // Return the line number (starting at 1) associated with this position,
 // ignoring any line directives (pragmas).
 func (p RawPos) Line() int {
-	return p.input.Column(p.off, false)
+	return p.input.Line(p.off, false)
 }
 
 // Return the column number (starting at 1) associated with this position,
@@ -120,3 +120,40 @@ func (p RawPos) Column() int {
 func (p RawPos) Adjusted() position.Position {
 	return p
 }
+
+// A half-open range [start, end) of input within a single Reader.
+//
+// Span implements the position.Span interface.
+type Span struct {
+	start Pos
+	end   Pos
+}
+
+// Return the span of input from p up to (but not including) end. Both positions
+// must be drawn from the same Reader.
+func (p Pos) To(end Pos) Span {
+	if p.input != end.input {
+		panic("Span endpoints are drawn from different readers")
+	}
+	return Span{start: p, end: end}
+}
+
+// Return a human-readable representation of this span.
+func (s Span) String() string {
+	return position.SpanString(s.start, s.end)
+}
+
+// Return the position of the first byte of the span.
+func (s Span) Start() position.Position {
+	return s.start
+}
+
+// Return the position just past the last byte of the span.
+func (s Span) End() position.Position {
+	return s.end
+}
+
+// Return the length of the span in bytes.
+func (s Span) Len() int {
+	return int(s.end.off - s.start.off)
+}
@@ -15,6 +15,7 @@ import (
 	"os"
 	"sort"
 	"sync"
+	"unicode/utf8"
 
 	"github.com/bitc-lang/go-compileutil/position"
 )
@@ -201,11 +202,12 @@ func (r *reader) ByteAt(o Offset) (byte, error) {
 
 // Return the byte slice covering the range [begin, end)
 func (r *reader) Content(begin, end Offset) ([]byte, error) {
-	if err := r.expandTo(end); err != nil {
+	// The range is half-open, so only bytes up to end-1 need to be present.
+	if err := r.expandTo(end - 1); err != nil {
 		return nil, err
 	}
 
-	if int(end) >= len(r.content) {
+	if int(end) > len(r.content) {
 		return nil, io.EOF
 	}
 
@@ -330,6 +332,36 @@ func (r *reader) Column(o Offset, adjusted bool) int {
 	return c
 }
 
+// Return the column number associated with offset o of r (starts at 1),
+// counting UTF-16 code units rather than bytes. This is the column convention
+// of the Language Server Protocol. Invalid UTF-8 bytes count as one unit each.
+//
+// Adjusts for line directives iff adjusted is true. Returns 0 where r.Column
+// would.
+func UTF16Column(r Reader, o Offset, adjusted bool) int {
+	_, l, c := r.NameLineAndColumn(o, adjusted)
+	if l == 0 || c < 1 {
+		return 0
+	}
+
+	text, err := r.Content(o-Offset(c-1), o)
+	if err != nil {
+		return 0
+	}
+
+	units := 0
+	for len(text) > 0 {
+		rn, size := utf8.DecodeRune(text)
+		if rn >= 0x10000 {
+			units += 2 // Encoded as a surrogate pair
+		} else {
+			units++
+		}
+		text = text[size:]
+	}
+	return 1 + units
+}
+
 func (r *reader) updateLines() {
 	for i := int(r.updatedTo); i < len(r.content); i++ {
 		if r.content[i] == '\n' {
@@ -124,3 +124,48 @@ func TestReaderPosition(t *testing.T) {
 	checkPos(t, r, 3, "reader/reader_test2:1:4 (3)")
 	checkPos(t, r, 20, "reader/reader_test2:2:1 (20)")
 }
+
+func TestReaderSpan(t *testing.T) {
+	r, err := OnString("abc\ndef")
+	if err != nil {
+		t.Fatalf("Error %v instantiating Reader on string", err)
+	}
+
+	start := r.Position().(*Pos)
+	r.SetOffset(6)
+	end := r.Position().(*Pos)
+
+	sp := start.To(*end)
+	if sp.Len() != 6 {
+		t.Fatalf("Unexpected span length %d", sp.Len())
+	}
+	if sp.String() != "<string>:1:1-2:3" {
+		t.Fatalf("Unexpected span string \"%s\"", sp)
+	}
+}
+
+func TestUTF16Column(t *testing.T) {
+	// 'é' is two bytes and one UTF-16 unit; '😀' is four bytes and two units.
+	r, err := OnString("ab\né😀x")
+	if err != nil {
+		t.Fatalf("Error %v instantiating Reader on string", err)
+	}
+
+	for _, tc := range []struct {
+		off      Offset
+		col, u16 int
+	}{
+		{1, 2, 2},
+		{3, 1, 1},
+		{5, 3, 2},
+		{9, 7, 4},
+		{10, 8, 5},
+	} {
+		if c := r.Column(tc.off, false); c != tc.col {
+			t.Fatalf("Column at offset %d is %d, expected %d", tc.off, c, tc.col)
+		}
+		if u := UTF16Column(r, tc.off, false); u != tc.u16 {
+			t.Fatalf("UTF-16 column at offset %d is %d, expected %d", tc.off, u, tc.u16)
+		}
+	}
+}